@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/leonardcser/web-mcp/internal/cache"
+	"github.com/leonardcser/web-mcp/internal/config"
+)
+
+const usage = `Usage: web-mcp-cache [--config file] [command]
+
+Without a command, runs the cache daemon.
+
+Commands:
+  serve                 Run the cache daemon
+  ls [prefix]           List cached keys, optionally only those starting with prefix
+  get <key>             Print the value of key
+  rm <key|prefix*>      Remove key, or every key starting with prefix when it ends in *
+  purge --expired       Remove expired entries
+  stats                 Show entry counts and sizes
+  compact               Rewrite the database file to reclaim free space
+
+Commands talk to the running daemon, or open the database file directly when
+the daemon is not running.
+`
+
+// runCommand executes a management subcommand, writing its output to
+// stdout and errors to stderr, and returns the process exit code.
+func runCommand(cfg config.Config, args []string, stdout, stderr io.Writer) int {
+	if args[0] == "serve" {
+		serve(cfg)
+		return 0
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+	kv, closeFn, err := openStore(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "web-mcp-cache: %v\n", err)
+		return 1
+	}
+	defer closeFn()
+	if err := cmd(kv, args[1:], stdout); err != nil {
+		fmt.Fprintf(stderr, "web-mcp-cache %s: %v\n", args[0], err)
+		var u usageError
+		if errors.As(err, &u) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// openStore connects to the running daemon, falling back to opening the
+// database file directly.
+func openStore(cfg config.Config) (store, func(), error) {
+	c := cache.NewClient(cfg.Cache.Socket)
+	if err := c.Ping(); err == nil {
+		return c, func() { _ = c.Close() }, nil
+	}
+	_ = c.Close()
+	db := cfg.Cache.DB
+	if _, err := os.Stat(db); err != nil {
+		return nil, nil, fmt.Errorf("daemon not running and no database at %s", db)
+	}
+	s, err := cache.Open(db, cache.Options{Bucket: "web"})
+	if err != nil {
+		return nil, nil, fmt.Errorf("daemon not running and cannot open %s: %w", db, err)
+	}
+	return s, func() { _ = s.Close() }, nil
+}
+
+var commands = map[string]func(kv store, args []string, out io.Writer) error{
+	"ls":      cmdList,
+	"get":     cmdGet,
+	"rm":      cmdRemove,
+	"purge":   cmdPurge,
+	"stats":   cmdStats,
+	"compact": cmdCompact,
+}
+
+func cmdList(kv store, args []string, out io.Writer) error {
+	if len(args) > 1 {
+		return usageError("usage: ls [prefix]")
+	}
+	prefix := ""
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+	entries, err := kv.Keys(prefix)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SIZE\tEXPIRES\tKEY")
+	for _, e := range entries {
+		expires := "never"
+		if !e.ExpiresAt.IsZero() {
+			expires = e.ExpiresAt.Local().Format(time.DateTime)
+		}
+		if e.Expired {
+			expires += " (expired)"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", e.Size, expires, e.Key)
+	}
+	return tw.Flush()
+}
+
+func cmdGet(kv store, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return usageError("usage: get <key>")
+	}
+	v, err := kv.Get(args[0])
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(v)
+	return err
+}
+
+func cmdRemove(kv store, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return usageError("usage: rm <key|prefix*>")
+	}
+	if prefix, ok := strings.CutSuffix(args[0], "*"); ok {
+		n, err := kv.DeletePrefix(prefix)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "removed %d entries\n", n)
+		return nil
+	}
+	return kv.Delete(args[0])
+}
+
+func cmdPurge(kv store, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	expired := fs.Bool("expired", false, "remove expired entries")
+	if err := fs.Parse(args); err != nil || !*expired || fs.NArg() > 0 {
+		return usageError("usage: purge --expired")
+	}
+	n, err := kv.PurgeExpired()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "removed %d expired entries\n", n)
+	return nil
+}
+
+func cmdStats(kv store, args []string, out io.Writer) error {
+	if len(args) > 0 {
+		return usageError("usage: stats")
+	}
+	st, err := kv.Stats()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "entries:   %d\n", st.Entries)
+	fmt.Fprintf(out, "expired:   %d\n", st.Expired)
+	fmt.Fprintf(out, "data size: %d bytes\n", st.Bytes)
+	fmt.Fprintf(out, "file size: %d bytes\n", st.FileSize)
+	if c, ok := kv.(*cache.Client); ok {
+		fmt.Fprintf(out, "daemon:    protocol version %d\n", c.ServerVersion())
+	} else {
+		fmt.Fprintln(out, "daemon:    not running")
+	}
+	return nil
+}
+
+func cmdCompact(kv store, args []string, out io.Writer) error {
+	if len(args) > 0 {
+		return usageError("usage: compact")
+	}
+	return kv.Compact()
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/leonardcser/web-mcp/internal/cache"
+	"github.com/leonardcser/web-mcp/internal/config"
+)
+
+// seedDB creates a cache database at path holding two fetched pages, a
+// search and an expired page.
+func seedDB(t *testing.T, path string) {
+	t.Helper()
+	s, err := cache.Open(path, cache.Options{Bucket: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"web_fetch|a", "web_fetch|b", "web_search|q"} {
+		if err := s.Put(k, []byte("value of "+k), time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	expired := binary.BigEndian.AppendUint64(nil, uint64(time.Now().Add(-time.Hour).Unix()))
+	if err := db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("web")).Put([]byte("web_fetch|old"), append(expired, "stale"...))
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testConfig points at a database in a fresh directory and a socket nothing
+// listens on.
+func testConfig(t *testing.T) config.Config {
+	t.Helper()
+	// Socket paths are limited to about 100 bytes, which t.TempDir can
+	// exceed.
+	dir, err := os.MkdirTemp("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	var cfg config.Config
+	cfg.Cache.DB = filepath.Join(dir, "cache.bbolt")
+	cfg.Cache.Socket = filepath.Join(dir, "s")
+	return cfg
+}
+
+// run runs a subcommand and returns its exit code and output.
+func run(t *testing.T, cfg config.Config, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := runCommand(cfg, args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// listedKeys returns the keys printed by ls.
+func listedKeys(t *testing.T, cfg config.Config) []string {
+	t.Helper()
+	code, out, errOut := run(t, cfg, "ls")
+	if code != 0 {
+		t.Fatalf("ls exited %d: %s", code, errOut)
+	}
+	var keys []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n")[1:] {
+		fields := strings.Fields(line)
+		keys = append(keys, fields[len(fields)-1])
+	}
+	return keys
+}
+
+// testCommands runs the subcommands against cfg, whichever way it is served.
+func testCommands(t *testing.T, cfg config.Config, daemon string) {
+	code, out, _ := run(t, cfg, "ls", "web_fetch|")
+	if code != 0 || !strings.HasPrefix(out, "SIZE") || !strings.Contains(out, "web_fetch|old") || !strings.Contains(out, "(expired)") || strings.Contains(out, "web_search") {
+		t.Errorf("ls web_fetch| = %d:\n%s", code, out)
+	}
+	if code, out, _ := run(t, cfg, "get", "web_fetch|a"); code != 0 || out != "value of web_fetch|a" {
+		t.Errorf("get = %d %q", code, out)
+	}
+	if code, _, errOut := run(t, cfg, "get", "missing"); code != 1 || !strings.Contains(errOut, "not found") {
+		t.Errorf("get missing = %d %q", code, errOut)
+	}
+	code, out, _ = run(t, cfg, "stats")
+	for _, want := range []string{"entries:   4\n", "expired:   1\n", daemon} {
+		if code != 0 || !strings.Contains(out, want) {
+			t.Errorf("stats = %d, lacks %q:\n%s", code, want, out)
+		}
+	}
+
+	if code, out, _ := run(t, cfg, "purge", "--expired"); code != 0 || out != "removed 1 expired entries\n" {
+		t.Errorf("purge --expired = %d %q", code, out)
+	}
+	if code, out, _ := run(t, cfg, "rm", "web_fetch|*"); code != 0 || out != "removed 2 entries\n" {
+		t.Errorf("rm prefix = %d %q", code, out)
+	}
+	if got := listedKeys(t, cfg); len(got) != 1 || got[0] != "web_search|q" {
+		t.Errorf("keys after rm = %q", got)
+	}
+	if code, _, _ := run(t, cfg, "rm", "web_search|q"); code != 0 {
+		t.Errorf("rm key = %d", code)
+	}
+	if got := listedKeys(t, cfg); len(got) != 0 {
+		t.Errorf("keys after removing the last one = %q", got)
+	}
+	if code, _, errOut := run(t, cfg, "compact"); code != 0 {
+		t.Errorf("compact = %d %s", code, errOut)
+	}
+}
+
+func TestCommandsOnDatabase(t *testing.T) {
+	cfg := testConfig(t)
+	seedDB(t, cfg.Cache.DB)
+	testCommands(t, cfg, "daemon:    not running")
+}
+
+func TestCommandsThroughDaemon(t *testing.T) {
+	cfg := testConfig(t)
+	seedDB(t, cfg.Cache.DB)
+	s, err := cache.Open(cfg.Cache.DB, cache.Options{Bucket: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	l, err := net.Listen("unix", cfg.Cache.Socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go handleConn(conn, s)
+		}
+	}()
+	// The daemon holds the database lock, so the commands can only
+	// succeed through it.
+	testCommands(t, cfg, "daemon:    protocol version 1")
+}
+
+func TestCommandErrors(t *testing.T) {
+	cfg := testConfig(t)
+	if code, _, errOut := run(t, cfg, "ls"); code != 1 || !strings.Contains(errOut, "no database at") {
+		t.Errorf("ls without a database = %d %q", code, errOut)
+	}
+	if code, _, errOut := run(t, cfg, "frobnicate"); code != 2 || !strings.Contains(errOut, `unknown command "frobnicate"`) {
+		t.Errorf("unknown command = %d %q", code, errOut)
+	}
+	if code, out, _ := run(t, cfg, "help"); code != 0 || out != usage {
+		t.Errorf("help = %d %q", code, out)
+	}
+
+	seedDB(t, cfg.Cache.DB)
+	for _, args := range [][]string{
+		{"ls", "a", "b"},
+		{"get"},
+		{"rm"},
+		{"purge"},
+		{"purge", "--all"},
+		{"purge", "--expired", "extra"},
+		{"stats", "x"},
+		{"compact", "x"},
+	} {
+		if code, _, errOut := run(t, cfg, args...); code != 2 || !strings.Contains(errOut, "usage: ") {
+			t.Errorf("%q = %d %q, want a usage error", args, code, errOut)
+		}
+	}
+}
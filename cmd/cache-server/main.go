@@ -2,18 +2,43 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
 	"net"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/leonardcser/web-mcp/internal/cache"
+	"github.com/leonardcser/web-mcp/internal/config"
 )
 
+// store is the cache served by the daemon.
+type store interface {
+	cache.KV
+	cache.Admin
+}
+
 func main() {
-	sock := defaultString(os.Getenv("WEB_MCP_CACHE_SOCK"), defaultSocketPath())
-	db := defaultString(os.Getenv("WEB_MCP_CACHE_DB"), defaultDBPath())
+	configPath := flag.String("config", "", "path to a YAML config file (default $WEB_MCP_CONFIG or ~/.config/web-mcp/config.yaml)")
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), usage) }
+	flag.Parse()
+	cfg, err := config.Load(config.Path(*configPath))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(cfg, flag.Args(), os.Stdout, os.Stderr))
+	}
+	serve(cfg)
+}
+
+// serve runs the cache daemon until the process is killed.
+func serve(cfg config.Config) {
+	sock := cfg.Cache.Socket
+	db := cfg.Cache.DB
 	_ = os.MkdirAll(filepath.Dir(db), 0o755)
 
 	// Ensure socket dir exists and remove stale socket
@@ -27,7 +52,14 @@ func main() {
 	defer l.Close()
 	_ = os.Chmod(sock, 0o600)
 
-	store, err := cache.Open(db, cache.Options{Bucket: "web", DefaultTTL: 15 * time.Minute})
+	store, err := cache.Open(db, cache.Options{
+		Bucket:          "web",
+		DefaultTTL:      cfg.Cache.DefaultTTL,
+		SweepInterval:   cfg.Cache.SweepInterval,
+		MaxEntries:      cfg.Cache.MaxEntries,
+		MaxBytes:        int64(cfg.Cache.MaxMB) * 1024 * 1024,
+		CompactInterval: cfg.Cache.CompactInterval,
+	})
 	if err != nil {
 		log.Fatal("failed to open cache database: ", err)
 	}
@@ -42,61 +74,99 @@ func main() {
 	}
 }
 
-func handleConn(conn net.Conn, kv cache.KV) {
+// handleConn serves requests on conn until it is closed. Requests without
+// an ID are answered inline, in order. Requests with an ID are handled
+// concurrently and may be answered out of order.
+func handleConn(conn net.Conn, kv store) {
 	defer conn.Close()
 	dec := json.NewDecoder(conn)
 	enc := json.NewEncoder(conn)
+	var wmu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	reply := func(resp cache.Response) {
+		wmu.Lock()
+		defer wmu.Unlock()
+		_ = enc.Encode(resp)
+	}
 	for {
 		var req cache.Request
 		if err := dec.Decode(&req); err != nil {
 			return
 		}
-		switch req.Op {
-		case "get":
-			v, err := kv.Get(req.Key)
-			if err != nil {
-				_ = enc.Encode(cache.Response{OK: false, Error: err.Error()})
-				continue
-			}
-			_ = enc.Encode(cache.Response{OK: true, Value: v})
-		case "put":
-			ttl := time.Duration(req.TTLSeconds) * time.Second
-			if err := kv.Put(req.Key, req.Value, ttl); err != nil {
-				_ = enc.Encode(cache.Response{OK: false, Error: err.Error()})
-				continue
-			}
-			_ = enc.Encode(cache.Response{OK: true})
-		case "delete":
-			if err := kv.Delete(req.Key); err != nil {
-				_ = enc.Encode(cache.Response{OK: false, Error: err.Error()})
-				continue
-			}
-			_ = enc.Encode(cache.Response{OK: true})
-		default:
-			_ = enc.Encode(cache.Response{OK: false, Error: "unknown op"})
+		if req.ID == 0 {
+			reply(handleRequest(req, kv))
+			continue
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply(handleRequest(req, kv))
+		}()
 	}
 }
 
-func defaultSocketPath() string {
-	home, _ := os.UserHomeDir()
-	if home == "" {
-		home = "."
-	}
-	return filepath.Join(home, ".cache", "web-mcp", "cache.sock")
+func handleRequest(req cache.Request, kv store) cache.Response {
+	resp := handleOp(req, kv)
+	resp.ID = req.ID
+	return resp
 }
 
-func defaultDBPath() string {
-	home, _ := os.UserHomeDir()
-	if home == "" {
-		home = "."
-	}
-	return filepath.Join(home, ".cache", "web-mcp", "cache.bbolt")
-}
-
-func defaultString(v, d string) string {
-	if v == "" {
-		return d
+func handleOp(req cache.Request, kv store) cache.Response {
+	switch req.Op {
+	case "hello":
+		v, err := cache.NegotiateVersion(req)
+		if err != nil {
+			return cache.ErrorResponse(err)
+		}
+		return cache.Response{OK: true, Version: v}
+	case "get":
+		v, err := kv.Get(req.Key)
+		if err != nil {
+			return cache.ErrorResponse(err)
+		}
+		return cache.Response{OK: true, Value: v}
+	case "put":
+		ttl := time.Duration(req.TTLSeconds) * time.Second
+		if err := kv.Put(req.Key, req.Value, ttl); err != nil {
+			return cache.ErrorResponse(err)
+		}
+		return cache.Response{OK: true}
+	case "delete":
+		if err := kv.Delete(req.Key); err != nil {
+			return cache.ErrorResponse(err)
+		}
+		return cache.Response{OK: true}
+	case "keys":
+		entries, err := kv.Keys(req.Key)
+		if err != nil {
+			return cache.ErrorResponse(err)
+		}
+		return cache.Response{OK: true, Entries: entries}
+	case "delete_prefix":
+		n, err := kv.DeletePrefix(req.Key)
+		if err != nil {
+			return cache.ErrorResponse(err)
+		}
+		return cache.Response{OK: true, Count: n}
+	case "purge_expired":
+		n, err := kv.PurgeExpired()
+		if err != nil {
+			return cache.ErrorResponse(err)
+		}
+		return cache.Response{OK: true, Count: n}
+	case "stats":
+		st, err := kv.Stats()
+		if err != nil {
+			return cache.ErrorResponse(err)
+		}
+		return cache.Response{OK: true, Stats: &st}
+	case "compact":
+		if err := kv.Compact(); err != nil {
+			return cache.ErrorResponse(err)
+		}
+		return cache.Response{OK: true}
+	default:
+		return cache.ErrorResponse(cache.ErrUnknownOp)
 	}
-	return v
 }
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"net"
+	"flag"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,28 +11,58 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
+	"github.com/leonardcser/web-mcp/internal/auth"
 	"github.com/leonardcser/web-mcp/internal/cache"
+	"github.com/leonardcser/web-mcp/internal/config"
 	"github.com/leonardcser/web-mcp/internal/logger"
 	tools "github.com/leonardcser/web-mcp/internal/tools"
 	web "github.com/leonardcser/web-mcp/internal/web"
 )
 
 func main() {
-	if err := logger.InitFromEnv(); err != nil {
+	configPath := flag.String("config", "", "path to a YAML config file (default $WEB_MCP_CONFIG or ~/.config/web-mcp/config.yaml)")
+	transport := flag.String("transport", "", "MCP transport: stdio, http (Streamable HTTP) or sse (overrides the config)")
+	addr := flag.String("addr", "", "listen address for the http and sse transports (overrides the config)")
+	flag.Parse()
+
+	path := config.Path(*configPath)
+	cfg, err := config.Load(path)
+	if err != nil {
+		panic(err)
+	}
+	if *transport != "" {
+		cfg.Server.Transport = *transport
+	}
+	if *addr != "" {
+		cfg.Server.Addr = *addr
+	}
+	if err := cfg.Validate(); err != nil {
+		panic(err)
+	}
+
+	if cfg.Log.Path != "" {
+		err = logger.Init(cfg.Log.Path)
+	} else {
+		err = logger.InitFromEnv()
+	}
+	if err != nil {
 		panic(err)
 	}
 	defer logger.Close()
 
 	logger.Infof("Starting Web MCP server")
+	if path != "" {
+		logger.Infof("Loaded configuration from %s", path)
+	}
 
 	// Connect to cache daemon; start it if needed, then connect.
-	sock := defaultSocketPath()
+	sock := cfg.Cache.Socket
 	logger.Infof("Attempting to connect to cache daemon at %s", sock)
 	client, err := connectCache(sock)
 	if err != nil {
 		logger.Warnf("Failed to connect to cache daemon: %v, attempting to start daemon", err)
 		// attempt to start daemon
-		if startErr := startCacheDaemon(); startErr != nil {
+		if startErr := startCacheDaemon(path, sock); startErr != nil {
 			logger.Errorf("Failed to start cache daemon: %v", startErr)
 		} else {
 			logger.Infof("Cache daemon started successfully")
@@ -47,22 +77,88 @@ func main() {
 			}
 			time.Sleep(200 * time.Millisecond)
 		}
-		if client == nil {
-			logger.Errorf("Failed to connect to cache daemon after startup attempt: %v", err)
-			panic(err)
-		}
 	}
-	logger.Infof("Successfully connected to cache daemon")
+	if client != nil {
+		logger.Infof("Successfully connected to cache daemon")
+	} else {
+		// Caching is an optimization; keep serving with an in-process cache
+		// and switch to the daemon if it comes up later.
+		logger.Warnf("Cache daemon unavailable (%v), using an in-memory cache until it is reachable", err)
+	}
+	// The daemon can also go away later; the fallback then serves from
+	// memory until it answers again.
+	kv := cache.NewFallback(
+		func() (cache.KV, error) { return connectCache(sock) },
+		cache.NewMemory(cfg.Cache.MemoryEntries, cfg.Cache.DefaultTTL),
+		cache.FallbackOptions{ProbeInterval: 30 * time.Second, Logf: logger.Warnf, Primary: client},
+	)
+
+	backend, err := web.NewSearchBackend(web.BackendConfig{
+		Name:     cfg.Search.Backend,
+		Endpoint: cfg.Search.Endpoint,
+		APIKey:   cfg.Search.APIKey,
+	})
+	if err != nil {
+		logger.Errorf("Invalid search backend configuration: %v", err)
+		panic(err)
+	}
+	logger.Infof("Using %s search backend", backend.Name())
+
+	policy, err := web.NewPolicy(cfg.Domains.Allow, cfg.Domains.Deny)
+	if err != nil {
+		logger.Errorf("Invalid domain policy: %v", err)
+		panic(err)
+	}
 
-	fetcher := web.NewFetcher(client, 15*time.Minute)
-	searcher := web.NewSearcher(client, 5*time.Minute)
+	fetcher, err := web.NewFetcher(kv, web.FetcherOptions{
+		DefaultTTL:      cfg.Fetch.DefaultTTL,
+		MinTTL:          cfg.Fetch.MinTTL,
+		MaxTTL:          cfg.Fetch.MaxTTL,
+		Allow:           cfg.Fetch.AllowAddresses,
+		Policy:          policy,
+		Timeout:         cfg.Fetch.Timeout,
+		MaxResponseSize: cfg.Fetch.MaxResponseSize,
+		MaxPDFSize:      cfg.Fetch.MaxPDFSize,
+		HostDelay:       cfg.Fetch.HostDelay,
+		HostParallelism: cfg.Fetch.HostParallelism,
+	})
+	if err != nil {
+		logger.Errorf("Invalid fetcher configuration: %v", err)
+		panic(err)
+	}
+	searcher := web.NewSearcher(backend, kv, web.SearcherOptions{TTL: cfg.Search.TTL, Policy: policy})
 	logger.Infof("Initialized web fetcher and searcher with cache client")
 
+	keys := make([]auth.Key, 0, len(cfg.Auth.Keys))
+	for _, k := range cfg.Auth.Keys {
+		keys = append(keys, auth.Key{Client: k.Client, Token: k.Token})
+	}
+	authn, err := auth.NewAuthenticator(keys)
+	if err != nil {
+		logger.Errorf("Invalid API keys: %v", err)
+		panic(err)
+	}
+	if cfg.Server.Transport != "stdio" && !authn.Enabled() {
+		logger.Warnf("No API keys configured; the %s transport accepts unauthenticated requests", cfg.Server.Transport)
+	}
+	limiter := auth.NewLimiter(auth.Limits{
+		RatePerMinute: cfg.Auth.RatePerMinute,
+		Daily: map[auth.Kind]int{
+			auth.Fetch:  cfg.Auth.DailyFetchQuota,
+			auth.Search: cfg.Auth.DailySearchQuota,
+		},
+	})
+
 	s := server.NewMCPServer(
 		"Web MCP",
 		"0.1.0",
 		server.WithRecovery(),
 		server.WithToolCapabilities(false),
+		server.WithToolHandlerMiddleware(limiter.Middleware(map[string]auth.Kind{
+			"web-fetch":      auth.Fetch,
+			"web-fetch-many": auth.Fetch,
+			"web-search":     auth.Search,
+		})),
 	)
 	logger.Infof("Created MCP server instance")
 
@@ -72,20 +168,58 @@ func main() {
 			"\nFunctionality:",
 			"- Takes a URL as input",
 			"- Fetches the URL content and parses it",
-			"- Returns the structured content including title, description, text, and links",
+			"- Returns the structured content including title, description, metadata (author, dates, canonical URL, language, preview image, JSON-LD/microdata), text, and links",
 			"\nUsage notes:",
 			"- If an MCP-provided web fetch tool is available, prefer using that tool instead",
 			"- The URL must be a fully-formed valid URL",
 			"- HTTP URLs will be automatically upgraded to HTTPS",
+			"- Private, loopback and link-local addresses (e.g. localhost, 10.0.0.0/8, 169.254.169.254) are blocked unless allowlisted by the server",
 			"- This tool is read-only and does not modify any files",
-			"- Includes a self-cleaning 15-minute cache for faster responses when repeatedly accessing the same URL",
-			"- When a URL redirects to a different host, the tool will inform you and provide the redirect URL in a special format",
+			"- Includes a self-cleaning cache that honors the page's HTTP caching headers (15 minutes when it has none) for faster responses when repeatedly accessing the same URL",
+			"- When a URL redirects to a different host, the redirect is not followed; the output starts with \"REDIRECT DETECTED\", lists the redirect chain and ends with the url to pass to web-fetch to continue",
+			"- Long pages are returned in chunks of max_length (default 20000 chars); when more content remains, the output ends with a next_start_index to pass as start_index on the next call, together with the other arguments to repeat",
+			"- Later chunks are served from the cache, so paging through a document does not refetch it; pages that forbid caching are refetched for every chunk, so their offsets can shift if the page changes",
+			"- PDF documents are supported; their text is returned page by page under \"## Page N\" headings",
+			"- By default the whole page is converted; use mode \"article\" to keep only the main content and drop navigation, sidebars and other page sections",
+			"- Use selector to read only part of a large page, e.g. \"#api-reference\" or \"table.changelog\"; when it matches nothing, the error lists similar ids and classes",
+			"- For long documentation pages, call with outline=true first to list the headings, then pass a heading anchor or title as section to read only that part",
+			"- Links are listed in document order with their anchor text, whether they are internal, same-site or external, and the heading they appear under",
 		)),
 		mcp.WithString("url", mcp.Required(), mcp.Description("The URL to fetch content from")),
+		mcp.WithString("mode", mcp.Enum("article", "full"), mcp.Description("Extraction mode: \"full\" (default) converts the whole page, \"article\" keeps only the main content")),
+		mcp.WithString("selector", mcp.Description("CSS selector, or XPath expression starting with \"/\", limiting the page to the matched elements; mode is ignored when set")),
+		mcp.WithBoolean("outline", mcp.Description("Return only the page's heading outline, with each heading's anchor and section length")),
+		mcp.WithString("section", mcp.Description("Return only the content under this heading, given by its anchor (e.g. \"#install\") or title; see outline")),
+		mcp.WithString("links", mcp.Enum("none", "internal", "all"), mcp.Description("Links to list: \"all\" (default), \"internal\" for links within the same site, or \"none\"")),
+		mcp.WithString("link_style", mcp.Enum("plain", "inline", "reference"), mcp.Description("How links in the text are rendered: \"plain\" (default) keeps only the anchor text, \"inline\" as [text](url), \"reference\" as [text][n] with the URLs listed at the end; links shown in the text are left out of the Links list")),
+		mcp.WithNumber("start_index", mcp.Min(0), mcp.Description("Offset to start reading from, in the selected unit (default 0)")),
+		mcp.WithNumber("max_length", mcp.Min(1), mcp.Description("Maximum amount of content to return, in the selected unit (default 20000 chars)")),
+		mcp.WithString("unit", mcp.Enum("chars", "tokens"), mcp.Description("Unit for start_index and max_length: \"chars\" (default) or \"tokens\" (estimated at ~4 chars per token)")),
 	)
 	s.AddTool(toolFetch, tools.WebFetchHandler(fetcher))
 	logger.Infof("Registered web-fetch tool")
 
+	toolFetchMany := mcp.NewTool("web-fetch-many",
+		mcp.WithDescription(multiline(
+			"Fetches several URLs in parallel and returns their parsed content in one response",
+			"\nFunctionality:",
+			"- Takes a list of up to 20 URLs, e.g. the links of several search results",
+			"- Fetches them concurrently and returns one section per URL, in the order given",
+			"- Failed URLs get an ERROR section; the other pages are still returned",
+			"\nUsage notes:",
+			"- Prefer this over several web-fetch calls when you already know which pages you need",
+			"- The pages share a budget of max_total_length characters (default 50000); short pages are shown in full and the rest is split among longer ones",
+			"- Truncated pages end with the web-fetch call that continues them",
+			"- Pages are parsed and cached exactly as with web-fetch",
+		)),
+		mcp.WithArray("urls", mcp.Required(), mcp.WithStringItems(), mcp.MinItems(1), mcp.MaxItems(20), mcp.Description("The URLs to fetch")),
+		mcp.WithString("mode", mcp.Enum("article", "full"), mcp.Description("Extraction mode: \"full\" (default) converts the whole page, \"article\" keeps only the main content")),
+		mcp.WithNumber("max_total_length", mcp.Min(1), mcp.Description("Total characters to return across all pages (default 50000)")),
+		mcp.WithNumber("concurrency", mcp.Min(1), mcp.Max(8), mcp.Description("Maximum number of pages fetched at once (default 4); requests to the same host are still rate limited")),
+	)
+	s.AddTool(toolFetchMany, tools.WebFetchManyHandler(fetcher))
+	logger.Infof("Registered web-fetch-many tool")
+
 	toolSearch := mcp.NewTool("web-search",
 		mcp.WithDescription(multiline(
 			"Allows you to search the web and use the results to inform responses",
@@ -95,16 +229,23 @@ func main() {
 			"- Use this tool for accessing information beyond your knowledge cutoff",
 			"- Searches are performed automatically within a single API call",
 			"\nUsage notes:",
-			"- Web search is only available in the US",
+			"- Results default to the US region; use region and language for other markets",
+			"- Use time_range to restrict results to recent pages",
+			"- Use page to go deeper into the results of the same query",
 			"- Account for Today's date in environment (e.g., use 2025 when appropriate)",
 		)),
 		mcp.WithString("query", mcp.Required(), mcp.Description("The search query to use")),
+		mcp.WithNumber("max_results", mcp.Min(1), mcp.Max(web.MaxSearchResults), mcp.Description("Maximum number of results to return (default 10, max 20)")),
+		mcp.WithString("region", mcp.Description("Region as a country-language pair, e.g. \"us-en\", \"de-de\", \"fr-fr\", or \"wt-wt\" for no region")),
+		mcp.WithString("language", mcp.Description("Language code for results, e.g. \"en\" or \"fr\" (defaults to the region's language)")),
+		mcp.WithString("time_range", mcp.Enum("day", "week", "month", "year"), mcp.Description("Only return pages published within this time range")),
+		mcp.WithString("safe_search", mcp.Enum("off", "moderate", "strict"), mcp.Description("Adult content filtering level (backend default when omitted)")),
+		mcp.WithNumber("page", mcp.Min(1), mcp.Max(web.MaxSearchPage), mcp.Description("Result page to return, starting at 1 (default 1)")),
 	)
 	s.AddTool(toolSearch, tools.WebSearchHandler(searcher))
 	logger.Infof("Registered web-search tool")
 
-	logger.Infof("Starting MCP server on stdio")
-	if err := server.ServeStdio(s); err != nil {
+	if err := serve(s, cfg.Server.Transport, cfg.Server.Addr, authn); err != nil {
 		logger.Errorf("server error: %v", err)
 	}
 }
@@ -112,28 +253,25 @@ func main() {
 // multiline joins lines with newlines for tool descriptions.
 func multiline(lines ...string) string { return strings.Join(lines, "\n") }
 
-func defaultSocketPath() string {
-	if s := os.Getenv("WEB_MCP_CACHE_SOCK"); s != "" {
-		return s
-	}
-	home, _ := os.UserHomeDir()
-	if home == "" {
-		home = "."
-	}
-	return filepath.Join(home, ".cache", "web-mcp", "cache.sock")
-}
-
+// connectCache returns a client for the daemon at sock once it has
+// completed the handshake, so a socket held by an incompatible daemon is
+// not mistaken for a working cache.
 func connectCache(sock string) (cache.KV, error) {
-	// quick probe
-	conn, err := net.DialTimeout("unix", sock, 200*time.Millisecond)
-	if err != nil {
+	c := cache.NewClient(sock)
+	if err := c.Ping(); err != nil {
+		_ = c.Close()
 		return nil, err
 	}
-	_ = conn.Close()
-	return cache.NewClient(sock), nil
+	return c, nil
 }
 
-func startCacheDaemon() error {
+// startCacheDaemon spawns web-mcp-cache, passing on the config file and
+// socket so the daemon listens where this server connects.
+func startCacheDaemon(configPath, sock string) error {
+	env := append(os.Environ(), "WEB_MCP_CACHE_SOCK="+sock)
+	if configPath != "" {
+		env = append(env, config.EnvPath+"="+configPath)
+	}
 	// 1) Try cache binary next to this server executable (works with absolute invocation)
 	if exePath, err := os.Executable(); err == nil {
 		exeDir := filepath.Dir(exePath)
@@ -142,7 +280,7 @@ func startCacheDaemon() error {
 			cmd := exec.Command(sibling)
 			cmd.Stdout = nil
 			cmd.Stderr = nil
-			cmd.Env = os.Environ()
+			cmd.Env = env
 			return cmd.Start()
 		}
 	}
@@ -152,7 +290,7 @@ func startCacheDaemon() error {
 		cmd := exec.Command(path)
 		cmd.Stdout = nil
 		cmd.Stderr = nil
-		cmd.Env = os.Environ()
+		cmd.Env = env
 		return cmd.Start()
 	}
 
@@ -161,7 +299,7 @@ func startCacheDaemon() error {
 		cmd := exec.Command("./web-mcp-cache")
 		cmd.Stdout = nil
 		cmd.Stderr = nil
-		cmd.Env = os.Environ()
+		cmd.Env = env
 		return cmd.Start()
 	}
 
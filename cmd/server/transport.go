@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/leonardcser/web-mcp/internal/auth"
+	"github.com/leonardcser/web-mcp/internal/logger"
+)
+
+// shutdownTimeout bounds how long in-flight HTTP requests may take to
+// finish after SIGTERM or SIGINT.
+const shutdownTimeout = 10 * time.Second
+
+// serve runs s over the selected transport until it fails or, for the HTTP
+// transports, until the process receives SIGTERM or SIGINT. HTTP requests
+// are authenticated by authn.
+func serve(s *server.MCPServer, transport, addr string, authn *auth.Authenticator) error {
+	switch transport {
+	case "stdio":
+		logger.Infof("Starting MCP server on stdio")
+		return server.ServeStdio(s)
+	case "http", "sse":
+		return serveHTTP(s, transport, addr, authn)
+	}
+	return fmt.Errorf("unknown transport %q: must be stdio, http or sse", transport)
+}
+
+// serveHTTP serves Streamable HTTP at /mcp, or legacy SSE at /sse and
+// /message, next to an unauthenticated /healthz endpoint.
+func serveHTTP(s *server.MCPServer, transport, addr string, authn *auth.Authenticator) error {
+	srv := &http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	shutdown := routes(s, transport, srv, authn)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	logger.Infof("Shutting down HTTP server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// routes installs the handlers of the HTTP transport on srv and returns the
+// function that shuts the transport down.
+func routes(s *server.MCPServer, transport string, srv *http.Server, authn *auth.Authenticator) func(context.Context) error {
+	mux := http.NewServeMux()
+	srv.Handler = mux
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("ok\n"))
+	})
+
+	if transport == "sse" {
+		sse := server.NewSSEServer(s, server.WithHTTPServer(srv), server.WithSSEContextFunc(authn.HTTPContextFunc))
+		mux.Handle(sse.CompleteSsePath(), authn.Middleware(sse))
+		mux.Handle(sse.CompleteMessagePath(), authn.Middleware(sse))
+		logger.Infof("Starting MCP server with SSE transport on %s (%s, %s)", srv.Addr, sse.CompleteSsePath(), sse.CompleteMessagePath())
+		return sse.Shutdown
+	}
+	streamable := server.NewStreamableHTTPServer(s,
+		server.WithStreamableHTTPServer(srv),
+		server.WithHTTPContextFunc(authn.HTTPContextFunc),
+	)
+	mux.Handle("/mcp", authn.Middleware(streamable))
+	logger.Infof("Starting MCP server with Streamable HTTP transport on %s (/mcp)", srv.Addr)
+	return streamable.Shutdown
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/leonardcser/web-mcp/internal/auth"
+)
+
+const testToken = "s3cret"
+
+// newTestServer serves an MCP server with a "whoami" tool, which returns
+// the client the call was attributed to, over transport.
+func newTestServer(t *testing.T, transport string, keys ...auth.Key) *httptest.Server {
+	t.Helper()
+	authn, err := auth.NewAuthenticator(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.NewMCPServer("test", "0.0.0")
+	s.AddTool(mcp.NewTool("whoami"), func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, _ := auth.ClientFrom(ctx)
+		return mcp.NewToolResultText(client), nil
+	})
+	srv := &http.Server{}
+	ts := httptest.NewUnstartedServer(nil)
+	shutdown := routes(s, transport, srv, authn)
+	ts.Config.Handler = srv.Handler
+	ts.Start()
+	t.Cleanup(func() {
+		_ = shutdown(context.Background())
+		ts.Close()
+	})
+	return ts
+}
+
+// rpc posts a JSON-RPC message to the Streamable HTTP endpoint.
+func rpc(t *testing.T, ts *httptest.Server, token, session, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if session != "" {
+		req.Header.Set(server.HeaderKeySessionID, session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+const initialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`
+
+func TestHealthzIsUnauthenticated(t *testing.T) {
+	for _, transport := range []string{"http", "sse"} {
+		ts := newTestServer(t, transport, auth.Key{Client: "alice", Token: testToken})
+		resp, err := http.Get(ts.URL + "/healthz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != "ok\n" {
+			t.Errorf("%s: /healthz = %d %q", transport, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestStreamableHTTP(t *testing.T) {
+	ts := newTestServer(t, "http", auth.Key{Client: "alice", Token: testToken})
+
+	for _, token := range []string{"", "wrong"} {
+		resp := rpc(t, ts, token, "", initialize)
+		if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("token %q: status %d, want 401 with a challenge", token, resp.StatusCode)
+		}
+	}
+
+	resp := rpc(t, ts, testToken, "", initialize)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("initialize: status %d", resp.StatusCode)
+	}
+	session := resp.Header.Get(server.HeaderKeySessionID)
+	resp = rpc(t, ts, testToken, session, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"whoami"}}`)
+	var out struct {
+		Result mcp.CallToolResult `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("tools/call: status %d: %v", resp.StatusCode, err)
+	}
+	if len(out.Result.Content) != 1 || out.Result.Content[0].(mcp.TextContent).Text != "alice" {
+		t.Errorf("whoami = %+v, want alice", out.Result.Content)
+	}
+}
+
+func TestSSE(t *testing.T) {
+	ts := newTestServer(t, "sse", auth.Key{Client: "alice", Token: testToken})
+
+	resp, err := http.Get(ts.URL + "/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("/sse without a key: status %d, want 401", resp.StatusCode)
+	}
+	resp, err = http.Post(ts.URL+"/message?sessionId=x", "application/json", strings.NewReader(initialize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("/message without a key: status %d, want 401", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/sse", nil)
+	req.Header.Set("X-API-Key", testToken)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("/sse: status %d", resp.StatusCode)
+	}
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		if data, ok := strings.CutPrefix(sc.Text(), "data: "); ok {
+			if !strings.Contains(data, "/message?sessionId=") {
+				t.Errorf("endpoint event = %q, want the message endpoint", data)
+			}
+			return
+		}
+	}
+	t.Fatalf("no endpoint event: %v", sc.Err())
+}
+
+func TestServeRejectsUnknownTransport(t *testing.T) {
+	err := serve(server.NewMCPServer("test", "0.0.0"), "websocket", "", nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown transport") {
+		t.Errorf("serve = %v, want an unknown transport error", err)
+	}
+}
+
+func TestServeHTTPShutsDownOnSignal(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	authn, _ := auth.NewAuthenticator(nil)
+	errc := make(chan error, 1)
+	go func() { errc <- serveHTTP(server.NewMCPServer("test", "0.0.0"), "http", addr, authn) }()
+
+	// The signal handler is installed before the server accepts
+	// connections, so once /healthz answers SIGTERM is safe to send.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get("http://" + addr + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server never came up: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("serveHTTP = %v, want a clean shutdown", err)
+		}
+	case <-time.After(shutdownTimeout + time.Second):
+		t.Fatal("serveHTTP did not return after SIGTERM")
+	}
+}
+
+func TestAnonymousCallsAreAttributedToTheRemoteAddress(t *testing.T) {
+	ts := newTestServer(t, "http")
+	resp := rpc(t, ts, "", "", initialize)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("initialize: status %d", resp.StatusCode)
+	}
+	session := resp.Header.Get(server.HeaderKeySessionID)
+	resp = rpc(t, ts, "", session, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"whoami"}}`)
+	var out struct {
+		Result mcp.CallToolResult `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("tools/call: status %d: %v", resp.StatusCode, err)
+	}
+	if len(out.Result.Content) != 1 || out.Result.Content[0].(mcp.TextContent).Text != "127.0.0.1" {
+		t.Errorf("whoami = %+v, want 127.0.0.1", out.Result.Content)
+	}
+}
@@ -0,0 +1,107 @@
+// Package auth authenticates HTTP clients of the MCP server and enforces
+// per-client rate limits and daily quotas on tool calls.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Key grants Client access with Token, sent either as a bearer token or in
+// the X-API-Key header.
+type Key struct {
+	Client string
+	Token  string
+}
+
+// Authenticator maps tokens to client identities.
+type Authenticator struct {
+	// clients is keyed by the SHA-256 of the token so lookups do not
+	// compare secrets byte by byte.
+	clients map[[sha256.Size]byte]string
+}
+
+func NewAuthenticator(keys []Key) (*Authenticator, error) {
+	a := &Authenticator{clients: make(map[[sha256.Size]byte]string, len(keys))}
+	for _, k := range keys {
+		h := sha256.Sum256([]byte(k.Token))
+		if other, dup := a.clients[h]; dup {
+			return nil, fmt.Errorf("clients %q and %q share the same token", other, k.Client)
+		}
+		a.clients[h] = k.Client
+	}
+	return a, nil
+}
+
+// Enabled reports whether any keys are configured. Without keys every
+// request is accepted anonymously.
+func (a *Authenticator) Enabled() bool { return a != nil && len(a.clients) > 0 }
+
+// Authenticate returns the client identified by the request's credentials.
+func (a *Authenticator) Authenticate(r *http.Request) (string, bool) {
+	token := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); token == "" && auth != "" {
+		scheme, rest, _ := strings.Cut(auth, " ")
+		if strings.EqualFold(scheme, "Bearer") {
+			token = strings.TrimSpace(rest)
+		}
+	}
+	if token == "" {
+		return "", false
+	}
+	client, ok := a.clients[sha256.Sum256([]byte(token))]
+	return client, ok
+}
+
+// Middleware rejects requests without valid credentials with 401. It is a
+// no-op when no keys are configured.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	if !a.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := a.Authenticate(r); !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="web-mcp"`)
+			http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HTTPContextFunc stores the client of r in ctx: the authenticated client,
+// or the remote IP address when r carries no valid credentials, so that
+// anonymous callers are limited too. It has the signature of the mcp-go
+// HTTP and SSE context functions.
+func (a *Authenticator) HTTPContextFunc(ctx context.Context, r *http.Request) context.Context {
+	if client, ok := a.Authenticate(r); ok {
+		return WithClient(ctx, client)
+	}
+	return WithClient(ctx, remoteIP(r))
+}
+
+// remoteIP returns the IP address of the peer that sent r.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type clientKey struct{}
+
+// WithClient returns a copy of ctx carrying the client identity.
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFrom returns the client identity stored in ctx.
+func ClientFrom(ctx context.Context) (string, bool) {
+	client, ok := ctx.Value(clientKey{}).(string)
+	return client, ok
+}
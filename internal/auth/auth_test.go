@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestAuthenticator(t *testing.T) *Authenticator {
+	t.Helper()
+	a, err := NewAuthenticator([]Key{{Client: "alice", Token: "a-token"}, {Client: "bob", Token: "b-token"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestAuthenticate(t *testing.T) {
+	a := newTestAuthenticator(t)
+	for _, tc := range []struct {
+		name   string
+		header map[string]string
+		client string
+	}{
+		{name: "bearer", header: map[string]string{"Authorization": "Bearer a-token"}, client: "alice"},
+		{name: "bearer scheme case", header: map[string]string{"Authorization": "bearer  b-token "}, client: "bob"},
+		{name: "api key header", header: map[string]string{"X-API-Key": "b-token"}, client: "bob"},
+		{name: "api key wins", header: map[string]string{"X-API-Key": "a-token", "Authorization": "Bearer b-token"}, client: "alice"},
+		{name: "basic scheme", header: map[string]string{"Authorization": "Basic a-token"}},
+		{name: "unknown token", header: map[string]string{"Authorization": "Bearer nope"}},
+		{name: "token case", header: map[string]string{"X-API-Key": "A-TOKEN"}},
+		{name: "empty bearer", header: map[string]string{"Authorization": "Bearer "}},
+		{name: "no credentials"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			for k, v := range tc.header {
+				r.Header.Set(k, v)
+			}
+			client, ok := a.Authenticate(r)
+			if client != tc.client || ok != (tc.client != "") {
+				t.Errorf("Authenticate = %q, %v; want %q", client, ok, tc.client)
+			}
+		})
+	}
+}
+
+func TestNewAuthenticatorRejectsSharedTokens(t *testing.T) {
+	if _, err := NewAuthenticator([]Key{{Client: "a", Token: "t"}, {Client: "b", Token: "t"}}); err == nil {
+		t.Error("NewAuthenticator accepted two clients with the same token")
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) })
+	serve := func(a *Authenticator, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		if token != "" {
+			r.Header.Set("X-API-Key", token)
+		}
+		w := httptest.NewRecorder()
+		a.Middleware(next).ServeHTTP(w, r)
+		return w
+	}
+
+	a := newTestAuthenticator(t)
+	if w := serve(a, "a-token"); w.Code != http.StatusTeapot {
+		t.Errorf("valid key: status %d", w.Code)
+	}
+	for _, token := range []string{"", "wrong"} {
+		w := serve(a, token)
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Bearer realm="web-mcp"` {
+			t.Errorf("key %q: status %d, challenge %q", token, w.Code, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+
+	open, err := NewAuthenticator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open.Enabled() {
+		t.Error("an authenticator without keys is enabled")
+	}
+	if w := serve(open, ""); w.Code != http.StatusTeapot {
+		t.Errorf("no keys configured: status %d", w.Code)
+	}
+}
+
+func TestHTTPContextFunc(t *testing.T) {
+	a := newTestAuthenticator(t)
+	for _, tc := range []struct {
+		remote, token, client string
+	}{
+		{"192.0.2.1:1234", "a-token", "alice"},
+		{"192.0.2.1:1234", "", "192.0.2.1"},
+		{"[2001:db8::1]:443", "wrong", "2001:db8::1"},
+		{"@", "", "@"},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		r.RemoteAddr = tc.remote
+		if tc.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		client, ok := ClientFrom(a.HTTPContextFunc(context.Background(), r))
+		if !ok || client != tc.client {
+			t.Errorf("%s with key %q: client %q, want %q", tc.remote, tc.token, client, tc.client)
+		}
+	}
+	if _, ok := ClientFrom(context.Background()); ok {
+		t.Error("a bare context carries a client")
+	}
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/leonardcser/web-mcp/internal/logger"
+	"github.com/leonardcser/web-mcp/internal/urls"
+)
+
+// Kind is the quota a tool call counts against.
+type Kind string
+
+const (
+	Fetch  Kind = "fetch"
+	Search Kind = "search"
+)
+
+// Limits bounds the tool calls of a single client. Zero disables a limit.
+type Limits struct {
+	// RatePerMinute is the sustained number of tool calls per minute. Up
+	// to this many calls may be made in a burst.
+	RatePerMinute int
+	// Daily caps the number of calls of each kind per UTC day.
+	Daily map[Kind]int
+}
+
+// QuotaError reports a call rejected by a Limiter.
+type QuotaError struct {
+	Client string
+	// Kind is empty when the rate limit was hit.
+	Kind       Kind
+	Limit      int
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	if e.Kind == "" {
+		return fmt.Sprintf("rate limit exceeded for client %q: at most %d tool calls per minute, retry in %s",
+			e.Client, e.Limit, e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("daily %s quota exceeded for client %q: limit is %d calls per day, resets in %s",
+		e.Kind, e.Client, e.Limit, e.RetryAfter.Round(time.Minute))
+}
+
+// Limiter enforces Limits per client. It is safe for concurrent use by
+// multiple goroutines.
+type Limiter struct {
+	limits Limits
+	now    func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]*usage
+}
+
+type usage struct {
+	// tokens and last implement a token bucket for the rate limit.
+	tokens float64
+	last   time.Time
+	day    string
+	counts map[Kind]int
+}
+
+func NewLimiter(limits Limits) *Limiter {
+	return &Limiter{limits: limits, now: time.Now, clients: make(map[string]*usage)}
+}
+
+// Allow records a call by client that counts n times against its daily
+// quota of kind, or returns a *QuotaError when it would exceed the limits.
+// Each call takes one unit of the rate limit regardless of n. Rejected calls
+// are not counted.
+func (l *Limiter) Allow(client string, kind Kind, n int) error {
+	now := l.now()
+	day := now.UTC().Format(time.DateOnly)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	u := l.clients[client]
+	if u == nil {
+		u = &usage{tokens: float64(l.limits.RatePerMinute), last: now, day: day, counts: make(map[Kind]int)}
+		l.clients[client] = u
+	}
+	if u.day != day {
+		u.day = day
+		clear(u.counts)
+	}
+
+	if rate := l.limits.RatePerMinute; rate > 0 {
+		perSec := float64(rate) / 60
+		u.tokens = min(float64(rate), u.tokens+now.Sub(u.last).Seconds()*perSec)
+		u.last = now
+		if u.tokens < 1 {
+			wait := time.Duration((1 - u.tokens) / perSec * float64(time.Second))
+			return &QuotaError{Client: client, Limit: rate, RetryAfter: wait}
+		}
+	}
+	if limit := l.limits.Daily[kind]; limit > 0 && u.counts[kind]+n > limit {
+		midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		return &QuotaError{Client: client, Kind: kind, Limit: limit, RetryAfter: midnight.Sub(now)}
+	}
+
+	if l.limits.RatePerMinute > 0 {
+		u.tokens--
+	}
+	u.counts[kind] += n
+	return nil
+}
+
+// Middleware attributes tool calls to the client in the request context,
+// logs them and enforces the limits. kinds maps tool names to the quota
+// they count against; other tools only count against the rate limit. Calls
+// with a "urls" list count once per distinct URL against the daily quota.
+// Calls without a client identity, which only happens over stdio, are not
+// limited.
+func (l *Limiter) Middleware(kinds map[string]Kind) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			client, ok := ClientFrom(ctx)
+			if !ok {
+				return next(ctx, req)
+			}
+			logger.Infof("Tool %s called by client %s", req.Params.Name, client)
+			n := max(len(urls.Unique(req.GetStringSlice("urls", nil))), 1)
+			if err := l.Allow(client, kinds[req.Params.Name], n); err != nil {
+				logger.Warnf("Rejected %s call by client %s: %v", req.Params.Name, client, err)
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return next(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// fakeClock is a settable time source for Limiter.now.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(limits Limits, start time.Time) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: start}
+	l := NewLimiter(limits)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestLimiterRate(t *testing.T) {
+	l, clock := newTestLimiter(Limits{RatePerMinute: 3}, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	// The full rate is available as a burst.
+	for i := range 3 {
+		if err := l.Allow("alice", Fetch, 1); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	err := l.Allow("alice", Fetch, 1)
+	var qe *QuotaError
+	if !errors.As(err, &qe) || qe.Kind != "" || qe.Limit != 3 || qe.RetryAfter != 20*time.Second {
+		t.Fatalf("fourth call: %v, want a rate limit error retrying in 20s", err)
+	}
+	if !strings.Contains(err.Error(), `rate limit exceeded for client "alice"`) {
+		t.Errorf("message %q", err)
+	}
+	// Other clients have their own bucket.
+	if err := l.Allow("bob", Fetch, 1); err != nil {
+		t.Errorf("bob: %v", err)
+	}
+	// One call refills every 20 seconds.
+	clock.advance(19 * time.Second)
+	if err := l.Allow("alice", Fetch, 1); err == nil {
+		t.Error("call allowed before a token refilled")
+	}
+	clock.advance(time.Second)
+	if err := l.Allow("alice", Fetch, 1); err != nil {
+		t.Errorf("call after refilling: %v", err)
+	}
+	// An idle client does not accumulate more than the burst.
+	clock.advance(time.Hour)
+	for i := range 3 {
+		if err := l.Allow("alice", Fetch, 1); err != nil {
+			t.Fatalf("call %d after idling: %v", i, err)
+		}
+	}
+	if err := l.Allow("alice", Fetch, 1); err == nil {
+		t.Error("burst after idling exceeded the rate")
+	}
+}
+
+func TestLimiterDailyQuota(t *testing.T) {
+	l, clock := newTestLimiter(Limits{Daily: map[Kind]int{Fetch: 5, Search: 1}}, time.Date(2025, 1, 1, 22, 0, 0, 0, time.UTC))
+	if err := l.Allow("alice", Fetch, 4); err != nil {
+		t.Fatal(err)
+	}
+	// A call that would overshoot is rejected and not counted.
+	err := l.Allow("alice", Fetch, 2)
+	var qe *QuotaError
+	if !errors.As(err, &qe) || qe.Kind != Fetch || qe.Limit != 5 || qe.RetryAfter != 2*time.Hour {
+		t.Fatalf("overshooting call: %v, want the fetch quota resetting in 2h", err)
+	}
+	if !strings.Contains(err.Error(), `daily fetch quota exceeded for client "alice"`) {
+		t.Errorf("message %q", err)
+	}
+	if err := l.Allow("alice", Fetch, 1); err != nil {
+		t.Errorf("call within the quota after a rejection: %v", err)
+	}
+	// Quotas are counted per kind; kinds without a quota are unlimited.
+	if err := l.Allow("alice", Search, 1); err != nil {
+		t.Errorf("search: %v", err)
+	}
+	if err := l.Allow("alice", Search, 1); err == nil {
+		t.Error("second search allowed over its quota")
+	}
+	if err := l.Allow("alice", "other", 100); err != nil {
+		t.Errorf("kind without a quota: %v", err)
+	}
+	// Counts reset at midnight UTC.
+	clock.advance(2 * time.Hour)
+	if err := l.Allow("alice", Fetch, 5); err != nil {
+		t.Errorf("call on the next day: %v", err)
+	}
+}
+
+func TestMiddlewareLimitsAnonymousCallers(t *testing.T) {
+	l := NewLimiter(Limits{RatePerMinute: 1})
+	handler := l.Middleware(nil)(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	call := func(ctx context.Context) bool {
+		t.Helper()
+		res, err := handler(ctx, mcp.CallToolRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return !res.IsError
+	}
+
+	// HTTPContextFunc keys unauthenticated requests by remote address.
+	anon := WithClient(context.Background(), "192.0.2.1")
+	if !call(anon) || call(anon) {
+		t.Error("an anonymous caller was not limited")
+	}
+	if !call(WithClient(context.Background(), "192.0.2.2")) {
+		t.Error("another address shared the first one's limit")
+	}
+	// Calls without any identity only come over stdio.
+	for range 3 {
+		if !call(context.Background()) {
+			t.Fatal("a call over stdio was limited")
+		}
+	}
+}
+
+func TestMiddlewareCountsDistinctURLs(t *testing.T) {
+	l := NewLimiter(Limits{Daily: map[Kind]int{Fetch: 3}})
+	handler := l.Middleware(map[string]Kind{"web-fetch-many": Fetch})(
+		func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("ok"), nil
+		})
+	call := func(urls ...any) *mcp.CallToolResult {
+		t.Helper()
+		var req mcp.CallToolRequest
+		req.Params.Name = "web-fetch-many"
+		req.Params.Arguments = map[string]any{"urls": urls}
+		res, err := handler(WithClient(context.Background(), "alice"), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	// Two distinct URLs, despite the duplicate and the blank entry.
+	if res := call("https://a.example", " https://a.example ", "", "https://b.example"); res.IsError {
+		t.Fatalf("first call rejected: %+v", res.Content)
+	}
+	if res := call("https://c.example", "https://c.example"); res.IsError {
+		t.Fatalf("second call rejected with one URL of quota left: %+v", res.Content)
+	}
+	if res := call("https://d.example"); !res.IsError {
+		t.Fatal("call over the daily quota was allowed")
+	}
+}
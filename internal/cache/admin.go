@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Admin is implemented by caches that can be inspected and maintained.
+// Both Store and Client implement it.
+type Admin interface {
+	// Keys lists the entries whose key starts with prefix, in key order.
+	Keys(prefix string) ([]EntryInfo, error)
+	// DeletePrefix removes every entry whose key starts with prefix and
+	// returns how many were removed.
+	DeletePrefix(prefix string) (int, error)
+	// PurgeExpired removes expired entries and returns how many were
+	// removed.
+	PurgeExpired() (int, error)
+	Stats() (Stats, error)
+	Compact() error
+}
+
+// EntryInfo describes a cached entry without its value.
+type EntryInfo struct {
+	Key  string `json:"key"`
+	Size int    `json:"size"`
+	// ExpiresAt is zero for entries that never expire.
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	Expired   bool      `json:"expired,omitempty"`
+}
+
+// Stats summarizes the contents of a cache.
+type Stats struct {
+	Entries int `json:"entries"`
+	Expired int `json:"expired"`
+	// Bytes is the total size of keys and values.
+	Bytes int64 `json:"bytes"`
+	// FileSize is the size of the database file on disk.
+	FileSize int64 `json:"file_size"`
+}
+
+// expiry decodes the expiration prefix of a stored value.
+func expiry(v []byte, now int64) (expiresAt time.Time, expired bool) {
+	if len(v) < 8 {
+		return time.Time{}, true
+	}
+	ts := int64(binary.BigEndian.Uint64(v[:8]))
+	if ts == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(ts, 0), now > ts
+}
+
+func (s *Store) Keys(prefix string) ([]EntryInfo, error) {
+	now := time.Now().Unix()
+	var out []EntryInfo
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(s.bucket).Cursor()
+		p := []byte(prefix)
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			exp, expired := expiry(v, now)
+			out = append(out, EntryInfo{Key: string(k), Size: max(len(v)-8, 0), ExpiresAt: exp, Expired: expired})
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (s *Store) DeletePrefix(prefix string) (int, error) {
+	return s.deleteWhere(func(k, v []byte, now int64) bool {
+		return bytes.HasPrefix(k, []byte(prefix))
+	})
+}
+
+func (s *Store) PurgeExpired() (int, error) {
+	return s.deleteWhere(func(k, v []byte, now int64) bool {
+		_, expired := expiry(v, now)
+		return expired
+	})
+}
+
+// deleteWhere removes every entry for which match returns true.
+func (s *Store) deleteWhere(match func(k, v []byte, now int64) bool) (int, error) {
+	now := time.Now().Unix()
+	var removed []string
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		if err := b.ForEach(func(k, v []byte) error {
+			if match(k, v, now) {
+				removed = append(removed, string(k))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		return s.deleteKeys(tx, removed)
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.forget(removed...)
+	return len(removed), nil
+}
+
+func (s *Store) Stats() (Stats, error) {
+	now := time.Now().Unix()
+	var st Stats
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).ForEach(func(k, v []byte) error {
+			st.Entries++
+			st.Bytes += int64(len(k) + len(v))
+			if _, expired := expiry(v, now); expired {
+				st.Expired++
+			}
+			return nil
+		})
+	})
+	if fi, statErr := os.Stat(s.path); statErr == nil {
+		st.FileSize = fi.Size()
+	}
+	return st, err
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAdmin(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "c.db"), Options{})
+	past := time.Now().Add(-time.Hour).Unix()
+	putRaw(t, s, "a|old", past)
+	if err := s.Put("a|new", []byte("12345"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("b|x", []byte("v"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := s.Keys("a|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []EntryInfo{
+		{Key: "a|new", Size: 5},
+		{Key: "a|old", Size: 1, ExpiresAt: time.Unix(past, 0), Expired: true},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Keys = %+v, want %+v", entries, want)
+	}
+
+	st, err := s.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Entries != 3 || st.Expired != 1 || st.Bytes != int64(5+13+5+9+3+9) || st.FileSize == 0 {
+		t.Errorf("Stats = %+v", st)
+	}
+
+	if n, err := s.PurgeExpired(); err != nil || n != 1 {
+		t.Errorf("PurgeExpired = %d, %v; want 1", n, err)
+	}
+	if n, err := s.DeletePrefix("a|"); err != nil || n != 1 {
+		t.Errorf("DeletePrefix = %d, %v; want 1", n, err)
+	}
+	if got := keys(t, s); !reflect.DeepEqual(got, []string{"b|x"}) {
+		t.Errorf("keys = %q, want only b|x", got)
+	}
+}
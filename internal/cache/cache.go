@@ -3,6 +3,8 @@ package cache
 import (
 	"encoding/binary"
 	"errors"
+	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -12,10 +14,22 @@ import (
 // Store provides a simple persistent KV cache with TTL semantics.
 // It is safe for concurrent use by multiple goroutines.
 type Store struct {
-	db         *bolt.DB
-	bucket     []byte
-	defaultTTL time.Duration
-	mu         sync.RWMutex
+	db     *bolt.DB
+	path   string
+	bucket []byte
+	opts   Options
+	mu     sync.RWMutex
+
+	// atime records the last access of each key for LRU eviction. Sweep
+	// and Close save the times of the keys in dirty to a companion bucket,
+	// from which Open loads them, so the order survives restarts.
+	atimeMu sync.Mutex
+	atime   map[string]int64
+	dirty   map[string]bool
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 type Options struct {
@@ -23,6 +37,20 @@ type Options struct {
 	Bucket string
 	// DefaultTTL is used when Put is called with ttl <= 0.
 	DefaultTTL time.Duration
+	// SweepInterval is how often expired entries are deleted and the size
+	// limits enforced. Zero disables the background sweeper.
+	SweepInterval time.Duration
+	// MaxEntries and MaxBytes bound the number of entries and the total
+	// size of keys and values. When a sweep finds the store over either
+	// limit, the least recently used entries are evicted. Zero means
+	// unlimited.
+	MaxEntries int
+	MaxBytes   int64
+	// CompactInterval is how often the database file is rewritten to
+	// return space freed by deleted entries to the filesystem. Compaction
+	// is skipped when less than a quarter of the file is free. Zero
+	// disables it.
+	CompactInterval time.Duration
 }
 
 var (
@@ -32,30 +60,107 @@ var (
 
 // Open initializes or opens a Store at the given path.
 func Open(path string, opts Options) (*Store, error) {
-	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	if opts.Bucket == "" {
+		opts.Bucket = "cache"
+	}
+	db, err := openDB(path, []byte(opts.Bucket))
 	if err != nil {
 		return nil, err
 	}
-	bucket := []byte("cache")
-	if opts.Bucket != "" {
-		bucket = []byte(opts.Bucket)
+	s := &Store{
+		db:     db,
+		path:   path,
+		bucket: []byte(opts.Bucket),
+		opts:   opts,
+		atime:  make(map[string]int64),
+		dirty:  make(map[string]bool),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.atimeBucket()).ForEach(func(k, v []byte) error {
+			if len(v) == 8 {
+				s.atime[string(k)] = int64(binary.BigEndian.Uint64(v))
+			}
+			return nil
+		})
+	}); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	go s.maintain()
+	return s, nil
+}
+
+// openDB opens the database at path and creates bucket and its access time
+// bucket. It is a variable so tests can make reopening fail.
+var openDB = func(path string, bucket []byte) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, err
 	}
 	if err := db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(bucket)
+		if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(atimeBucketName(bucket))
 		return err
 	}); err != nil {
 		_ = db.Close()
 		return nil, err
 	}
-	return &Store{db: db, bucket: bucket, defaultTTL: opts.DefaultTTL}, nil
+	return db, nil
+}
+
+// atimeBucketName names the bucket holding the access times of the entries
+// of bucket.
+func atimeBucketName(bucket []byte) []byte {
+	return append(append([]byte(nil), bucket...), ".atime"...)
 }
 
-// Close closes the underlying database.
+func (s *Store) atimeBucket() []byte { return atimeBucketName(s.bucket) }
+
+// Close stops background maintenance and closes the underlying database.
 func (s *Store) Close() error {
 	if s == nil || s.db == nil {
 		return nil
 	}
-	return s.db.Close()
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saveErr := s.db.Update(s.saveAtimes)
+	return errors.Join(saveErr, s.db.Close())
+}
+
+// maintain runs the sweeper and compactor until Close is called.
+func (s *Store) maintain() {
+	defer close(s.done)
+	var sweep, compact <-chan time.Time
+	if s.opts.SweepInterval > 0 {
+		t := time.NewTicker(s.opts.SweepInterval)
+		defer t.Stop()
+		sweep = t.C
+	}
+	if s.opts.CompactInterval > 0 {
+		t := time.NewTicker(s.opts.CompactInterval)
+		defer t.Stop()
+		compact = t.C
+	}
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-sweep:
+			_, _ = s.Sweep()
+		case <-compact:
+			if s.reclaimable() {
+				_ = s.Compact()
+			}
+		}
+	}
 }
 
 // Put stores value with an absolute expiration computed as now+ttl.
@@ -63,7 +168,7 @@ func (s *Store) Close() error {
 func (s *Store) Put(key string, value []byte, ttl time.Duration) error {
 	expiresAt := int64(0)
 	if ttl <= 0 {
-		ttl = s.defaultTTL
+		ttl = s.opts.DefaultTTL
 	}
 	if ttl > 0 {
 		expiresAt = time.Now().Add(ttl).Unix()
@@ -75,10 +180,14 @@ func (s *Store) Put(key string, value []byte, ttl time.Duration) error {
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.db.Update(func(tx *bolt.Tx) error {
+	if err := s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(s.bucket)
 		return b.Put([]byte(key), buf)
-	})
+	}); err != nil {
+		return err
+	}
+	s.touch(key)
+	return nil
 }
 
 // Get returns cached value if present and not expired.
@@ -111,6 +220,7 @@ func (s *Store) Get(key string) ([]byte, error) {
 	if expired {
 		return nil, ErrExpired
 	}
+	s.touch(key)
 	return out, nil
 }
 
@@ -118,7 +228,201 @@ func (s *Store) Get(key string) ([]byte, error) {
 func (s *Store) Delete(key string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket(s.bucket).Delete([]byte(key))
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		return s.deleteKeys(tx, []string{key})
+	}); err != nil {
+		return err
+	}
+	s.forget(key)
+	return nil
+}
+
+// deleteKeys removes keys and their access times.
+func (s *Store) deleteKeys(tx *bolt.Tx, keys []string) error {
+	b, at := tx.Bucket(s.bucket), tx.Bucket(s.atimeBucket())
+	for _, k := range keys {
+		if err := b.Delete([]byte(k)); err != nil {
+			return err
+		}
+		if err := at.Delete([]byte(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) touch(key string) {
+	s.atimeMu.Lock()
+	s.atime[key] = time.Now().UnixNano()
+	s.dirty[key] = true
+	s.atimeMu.Unlock()
+}
+
+func (s *Store) forget(keys ...string) {
+	s.atimeMu.Lock()
+	for _, k := range keys {
+		delete(s.atime, k)
+		delete(s.dirty, k)
+	}
+	s.atimeMu.Unlock()
+}
+
+// saveAtimes writes the access times changed since the last save. Keys
+// deleted in the meantime are skipped.
+func (s *Store) saveAtimes(tx *bolt.Tx) error {
+	s.atimeMu.Lock()
+	defer s.atimeMu.Unlock()
+	b, at := tx.Bucket(s.bucket), tx.Bucket(s.atimeBucket())
+	for k := range s.dirty {
+		if b.Get([]byte(k)) == nil {
+			continue
+		}
+		// bbolt holds on to values until the transaction commits, so each
+		// needs its own buffer.
+		buf := binary.BigEndian.AppendUint64(nil, uint64(s.atime[k]))
+		if err := at.Put([]byte(k), buf); err != nil {
+			return err
+		}
+	}
+	clear(s.dirty)
+	return nil
+}
+
+// Sweep deletes expired entries, then evicts the least recently used
+// entries until the store is within MaxEntries and MaxBytes. Entries never
+// accessed since their access time was first recorded go first, in key
+// order. It returns the number of entries removed.
+func (s *Store) Sweep() (int, error) {
+	type entry struct {
+		key   string
+		size  int64
+		atime int64
+	}
+	now := time.Now().Unix()
+	var removed []string
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		var live []entry
+		var total int64
+		s.atimeMu.Lock()
+		err := b.ForEach(func(k, v []byte) error {
+			if len(v) < 8 {
+				removed = append(removed, string(k))
+				return nil
+			}
+			expiresAt := int64(binary.BigEndian.Uint64(v[:8]))
+			if expiresAt > 0 && now > expiresAt {
+				removed = append(removed, string(k))
+				return nil
+			}
+			e := entry{key: string(k), size: int64(len(k) + len(v)), atime: s.atime[string(k)]}
+			live = append(live, e)
+			total += e.size
+			return nil
+		})
+		s.atimeMu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		overLimit := func() bool {
+			return (s.opts.MaxEntries > 0 && len(live) > s.opts.MaxEntries) ||
+				(s.opts.MaxBytes > 0 && total > s.opts.MaxBytes)
+		}
+		if overLimit() {
+			sort.SliceStable(live, func(i, j int) bool { return live[i].atime < live[j].atime })
+			for overLimit() {
+				removed = append(removed, live[0].key)
+				total -= live[0].size
+				live = live[1:]
+			}
+		}
+
+		// Keys are deleted after iterating; deleting inside ForEach is not
+		// allowed by bbolt.
+		if err := s.deleteKeys(tx, removed); err != nil {
+			return err
+		}
+		s.forget(removed...)
+		return s.saveAtimes(tx)
 	})
+	if err != nil {
+		return 0, err
+	}
+	return len(removed), nil
+}
+
+// reclaimable reports whether at least a quarter of the database file is
+// free pages, which is when compaction is worthwhile.
+func (s *Store) reclaimable() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fi, err := os.Stat(s.path)
+	if err != nil || fi.Size() == 0 {
+		return false
+	}
+	stats := s.db.Stats()
+	free := int64(stats.FreePageN+stats.PendingPageN) * int64(s.db.Info().PageSize)
+	return free*4 >= fi.Size()
+}
+
+// Compact rewrites the database into a new file containing only live
+// pages and swaps it in place of the old one. Readers and writers block
+// for the duration. When the compacted file cannot be swapped in or opened,
+// the original file is restored.
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp := s.path + ".compact"
+	_ = os.Remove(tmp)
+	dst, err := bolt.Open(tmp, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+	if err := bolt.Compact(dst, s.db, 1<<20); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := s.db.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	backup := s.path + ".orig"
+	if err := os.Rename(s.path, backup); err != nil {
+		_ = os.Remove(tmp)
+		return s.reopen(err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		_ = os.Remove(tmp)
+		_ = os.Rename(backup, s.path)
+		return s.reopen(err)
+	}
+	db, err := openDB(s.path, s.bucket)
+	if err != nil {
+		_ = os.Rename(backup, s.path)
+		return s.reopen(err)
+	}
+	_ = os.Remove(backup)
+	s.db = db
+	return nil
+}
+
+// reopen opens the database at s.path again after a failed compaction and
+// returns cause. The store stays closed only if that fails too.
+func (s *Store) reopen(cause error) error {
+	db, err := openDB(s.path, s.bucket)
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+	s.db = db
+	return cause
 }
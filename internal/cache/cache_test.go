@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestStore(t *testing.T, path string, opts Options) *Store {
+	t.Helper()
+	s, err := Open(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// putRaw writes an entry that expires at expiresAt (Unix seconds, 0 for
+// never) without recording an access.
+func putRaw(t *testing.T, s *Store, key string, expiresAt int64) {
+	t.Helper()
+	buf := make([]byte, 8, 9)
+	binary.BigEndian.PutUint64(buf, uint64(expiresAt))
+	buf = append(buf, 'v')
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Put([]byte(key), buf)
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// keys returns every key of s in order.
+func keys(t *testing.T, s *Store) []string {
+	t.Helper()
+	entries, err := s.Keys("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.Key)
+	}
+	return out
+}
+
+// putInOrder stores keys one after the other, so each has a later access
+// time than the previous one.
+func putInOrder(t *testing.T, s *Store, keys ...string) {
+	t.Helper()
+	for _, k := range keys {
+		if err := s.Put(k, []byte("value of "+k), 0); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSweepRemovesExpiredEntries(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "c.db"), Options{})
+	putRaw(t, s, "old", time.Now().Add(-time.Hour).Unix())
+	putRaw(t, s, "forever", 0)
+	putRaw(t, s, "fresh", time.Now().Add(time.Hour).Unix())
+
+	if _, err := s.Get("old"); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Get(old) err = %v, want ErrExpired", err)
+	}
+	n, err := s.Sweep()
+	if err != nil || n != 1 {
+		t.Fatalf("Sweep = %d, %v; want 1 removed", n, err)
+	}
+	if got, want := keys(t, s), []string{"forever", "fresh"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys = %q, want %q", got, want)
+	}
+}
+
+func TestSweepEvictsLeastRecentlyUsed(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "c.db"), Options{MaxEntries: 2})
+	putInOrder(t, s, "a", "b", "c")
+	if _, err := s.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Sweep(); err != nil || n != 1 {
+		t.Fatalf("Sweep = %d, %v; want 1 evicted", n, err)
+	}
+	if got, want := keys(t, s), []string{"a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys = %q, want %q", got, want)
+	}
+}
+
+func TestSweepEvictsUntilWithinMaxBytes(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "c.db"), Options{})
+	putInOrder(t, s, "a", "b", "c", "d")
+	// Each entry is 1+8+10 bytes; keep room for two.
+	s.opts.MaxBytes = 2 * 19
+	if n, err := s.Sweep(); err != nil || n != 2 {
+		t.Fatalf("Sweep = %d, %v; want 2 evicted", n, err)
+	}
+	if got, want := keys(t, s), []string{"c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys = %q, want %q", got, want)
+	}
+}
+
+func TestAccessOrderSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.db")
+	s, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	putInOrder(t, s, "a", "b", "c")
+	if _, err := s.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openTestStore(t, path, Options{MaxEntries: 1})
+	if _, err := s.Sweep(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := keys(t, s), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys after reopening = %q, want %q", got, want)
+	}
+}
+
+func TestSweepEvictsUnaccessedEntriesInKeyOrder(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "c.db"), Options{MaxEntries: 2})
+	for _, k := range []string{"d", "b", "a", "c"} {
+		putRaw(t, s, k, 0)
+	}
+	putInOrder(t, s, "e")
+	if n, err := s.Sweep(); err != nil || n != 3 {
+		t.Fatalf("Sweep = %d, %v; want 3 evicted", n, err)
+	}
+	if got, want := keys(t, s), []string{"d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys = %q, want %q", got, want)
+	}
+}
+
+func TestDeleteForgetsAccessTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.db")
+	s := openTestStore(t, path, Options{})
+	putInOrder(t, s, "a", "b")
+	if _, err := s.Sweep(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.DeletePrefix("b"); err != nil || n != 1 {
+		t.Fatalf("DeletePrefix = %d, %v", n, err)
+	}
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		if k, _ := tx.Bucket(s.atimeBucket()).Cursor().First(); k != nil {
+			t.Errorf("access time of %q outlived its entry", k)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackgroundSweeper(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "c.db"), Options{SweepInterval: 5 * time.Millisecond})
+	putRaw(t, s, "old", time.Now().Add(-time.Hour).Unix())
+	deadline := time.Now().Add(2 * time.Second)
+	for len(keys(t, s)) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the sweeper never removed the expired entry")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.db")
+	s := openTestStore(t, path, Options{})
+	big := []byte(strings.Repeat("x", 64*1024))
+	for i := range 64 {
+		if err := s.Put(string(rune('A'+i)), big, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.DeletePrefix(""); err != nil {
+		t.Fatal(err)
+	}
+	putInOrder(t, s, "kept")
+	if !s.reclaimable() {
+		t.Fatal("a mostly deleted database is not reclaimable")
+	}
+	before := fileSize(t, path)
+
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if after := fileSize(t, path); after >= before {
+		t.Errorf("file size went from %d to %d bytes", before, after)
+	}
+	if v, err := s.Get("kept"); err != nil || string(v) != "value of kept" {
+		t.Errorf("Get after Compact = %q, %v", v, err)
+	}
+	if _, err := os.Stat(path + ".orig"); !os.IsNotExist(err) {
+		t.Errorf("the original file was left behind: %v", err)
+	}
+}
+
+func TestCompactRestoresOriginalWhenReopenFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.db")
+	s := openTestStore(t, path, Options{})
+	putInOrder(t, s, "a")
+
+	open := openDB
+	defer func() { openDB = open }()
+	failed := false
+	openDB = func(p string, bucket []byte) (*bolt.DB, error) {
+		if !failed {
+			failed = true
+			return nil, errors.New("disk on fire")
+		}
+		return open(p, bucket)
+	}
+
+	if err := s.Compact(); err == nil || !strings.Contains(err.Error(), "disk on fire") {
+		t.Fatalf("Compact err = %v, want the reopen failure", err)
+	}
+	if v, err := s.Get("a"); err != nil || string(v) != "value of a" {
+		t.Fatalf("Get after a failed Compact = %q, %v", v, err)
+	}
+	if err := s.Put("b", []byte("v"), 0); err != nil {
+		t.Fatalf("Put after a failed Compact: %v", err)
+	}
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fi.Size()
+}
@@ -2,97 +2,334 @@ package cache
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Client implements KV over a Unix socket.
+const (
+	// clientConns is the number of connections a Client multiplexes
+	// requests over.
+	clientConns = 4
+	dialTimeout = 500 * time.Millisecond
+	// requestTimeout bounds how long a single request waits for its
+	// response before the connection is considered broken.
+	requestTimeout = 5 * time.Second
+	// adminTimeout is used instead for admin ops that scan or rewrite the
+	// whole database.
+	adminTimeout = 5 * time.Minute
+)
+
+var errClientClosed = errors.New("cache: client closed")
+
+// errLegacyDaemon is reported for a daemon that answers without request
+// IDs, which predates the handshake and multiplexing.
+var errLegacyDaemon = errors.New("cache: daemon predates protocol version 1; restart it")
+
+// Client implements KV over a Unix socket. It keeps a small pool of
+// long-lived connections and tags every request with an ID, so many
+// goroutines can have requests in flight on the same connection. Broken
+// connections are redialed on the next request, and a request that fails
+// because its connection broke is retried once.
 type Client struct {
 	socketPath string
+	nextID     atomic.Uint64
+	nextConn   atomic.Uint32
+	// serverVersion is the protocol version negotiated with the daemon.
+	serverVersion atomic.Int32
+	closed        atomic.Bool
+
+	slots [clientConns]poolSlot
+}
+
+// poolSlot holds one pooled connection. Each slot is locked on its own, so
+// a slow dial or handshake only holds up requests assigned to that slot.
+type poolSlot struct {
+	mu sync.Mutex
+	cc *clientConn
 }
 
 func NewClient(socketPath string) *Client {
 	return &Client{socketPath: socketPath}
 }
 
-func (c *Client) withConn(fn func(conn net.Conn) error) error {
-	conn, err := net.DialTimeout("unix", c.socketPath, 500*time.Millisecond)
+// Close closes all pooled connections. In-flight requests fail.
+func (c *Client) Close() error {
+	c.closed.Store(true)
+	for i := range c.slots {
+		s := &c.slots[i]
+		s.mu.Lock()
+		if s.cc != nil {
+			s.cc.fail(errClientClosed)
+			s.cc = nil
+		}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// conn returns a live connection from the pool, dialing a new one when
+// the selected slot is empty or broken.
+func (c *Client) conn() (*clientConn, error) {
+	s := &c.slots[c.nextConn.Add(1)%clientConns]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.closed.Load() {
+		return nil, errClientClosed
+	}
+	if s.cc != nil && !s.cc.broken() {
+		return s.cc, nil
+	}
+	nc, err := net.DialTimeout("unix", c.socketPath, dialTimeout)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	cc := newClientConn(nc)
+	if err := c.hello(cc); err != nil {
+		cc.fail(err)
+		return nil, err
 	}
-	defer conn.Close()
-	return fn(conn)
+	s.cc = cc
+	return cc, nil
 }
 
-func (c *Client) Get(key string) ([]byte, error) {
-	var out []byte
-	err := c.withConn(func(conn net.Conn) error {
-		enc := json.NewEncoder(conn)
-		dec := json.NewDecoder(conn)
-		req := Request{Op: "get", Key: key}
-		if err := enc.Encode(&req); err != nil {
-			return err
+// hello performs the version handshake on a new connection and refuses
+// daemons that share no protocol version with the client.
+func (c *Client) hello(cc *clientConn) error {
+	req := Request{ID: c.nextID.Add(1), Op: "hello", Version: ProtocolVersion, MinVersion: MinProtocolVersion}
+	resp, err := cc.roundTrip(req, requestTimeout)
+	if err != nil {
+		return err
+	}
+	if err := resp.Err(); err != nil {
+		if errors.Is(err, ErrUnsupportedVersion) {
+			return fmt.Errorf("%w; restart it", err)
 		}
-		var resp Response
-		if err := dec.Decode(&resp); err != nil {
-			return err
+		return err
+	}
+	if resp.Version < MinProtocolVersion || resp.Version > ProtocolVersion {
+		return fmt.Errorf("cache: daemon chose protocol version %d, want %d to %d; restart it", resp.Version, MinProtocolVersion, ProtocolVersion)
+	}
+	c.serverVersion.Store(int32(resp.Version))
+	return nil
+}
+
+// Ping opens a connection to the daemon, unless one is already open, and
+// reports whether the handshake succeeded.
+func (c *Client) Ping() error {
+	_, err := c.conn()
+	return err
+}
+
+// ServerVersion returns the protocol version negotiated on the most recently
+// opened connection. It is 0 before the first request.
+func (c *Client) ServerVersion() int {
+	return int(c.serverVersion.Load())
+}
+
+// do sends req and waits for its response, retrying once on a fresh
+// connection when the first one turns out to be broken. All operations are
+// idempotent, so a retry is safe even if the first attempt was applied.
+func (c *Client) do(req Request) (Response, error) {
+	return c.doTimeout(req, requestTimeout)
+}
+
+func (c *Client) doTimeout(req Request, timeout time.Duration) (Response, error) {
+	var resp Response
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var cc *clientConn
+		cc, err = c.conn()
+		if err != nil {
+			return Response{}, err
 		}
-		if !resp.OK {
-			if resp.Error == "cache: not found" {
-				return ErrNotFound
-			}
-			if resp.Error == "cache: expired" {
-				return ErrExpired
-			}
-			return errorsNew(resp.Error)
+		req.ID = c.nextID.Add(1)
+		resp, err = cc.roundTrip(req, timeout)
+		if err == nil || !errors.Is(err, errConnBroken) {
+			return resp, err
 		}
-		out = append([]byte(nil), resp.Value...)
-		return nil
-	})
-	return out, err
+	}
+	return Response{}, err
+}
+
+func (c *Client) Get(key string) ([]byte, error) {
+	resp, err := c.do(Request{Op: "get", Key: key})
+	if err != nil {
+		return nil, err
+	}
+	if err := resp.Err(); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
 }
 
 func (c *Client) Put(key string, value []byte, ttl time.Duration) error {
-	return c.withConn(func(conn net.Conn) error {
-		enc := json.NewEncoder(conn)
-		dec := json.NewDecoder(conn)
-		req := Request{Op: "put", Key: key, Value: value, TTLSeconds: int64(ttl / time.Second)}
-		if err := enc.Encode(&req); err != nil {
-			return err
-		}
-		var resp Response
-		if err := dec.Decode(&resp); err != nil {
-			return err
-		}
-		if !resp.OK {
-			return errorsNew(resp.Error)
-		}
-		return nil
-	})
+	resp, err := c.do(Request{Op: "put", Key: key, Value: value, TTLSeconds: int64(ttl / time.Second)})
+	if err != nil {
+		return err
+	}
+	return resp.Err()
 }
 
 func (c *Client) Delete(key string) error {
-	return c.withConn(func(conn net.Conn) error {
-		enc := json.NewEncoder(conn)
-		dec := json.NewDecoder(conn)
-		req := Request{Op: "delete", Key: key}
-		if err := enc.Encode(&req); err != nil {
-			return err
-		}
+	resp, err := c.do(Request{Op: "delete", Key: key})
+	if err != nil {
+		return err
+	}
+	return resp.Err()
+}
+
+// admin sends an admin op and returns its response, converting failures
+// into errors.
+func (c *Client) admin(req Request) (Response, error) {
+	resp, err := c.doTimeout(req, adminTimeout)
+	if err != nil {
+		return Response{}, err
+	}
+	if err := resp.Err(); err != nil {
+		return Response{}, err
+	}
+	return resp, nil
+}
+
+func (c *Client) Keys(prefix string) ([]EntryInfo, error) {
+	resp, err := c.admin(Request{Op: "keys", Key: prefix})
+	return resp.Entries, err
+}
+
+func (c *Client) DeletePrefix(prefix string) (int, error) {
+	resp, err := c.admin(Request{Op: "delete_prefix", Key: prefix})
+	return resp.Count, err
+}
+
+func (c *Client) PurgeExpired() (int, error) {
+	resp, err := c.admin(Request{Op: "purge_expired"})
+	return resp.Count, err
+}
+
+func (c *Client) Stats() (Stats, error) {
+	resp, err := c.admin(Request{Op: "stats"})
+	if err != nil || resp.Stats == nil {
+		return Stats{}, err
+	}
+	return *resp.Stats, nil
+}
+
+func (c *Client) Compact() error {
+	_, err := c.admin(Request{Op: "compact"})
+	return err
+}
+
+var errConnBroken = errors.New("cache: connection broken")
+
+// clientConn is one multiplexed connection. Writes are serialized by wmu;
+// a single reader goroutine dispatches responses to waiting callers by ID.
+type clientConn struct {
+	conn net.Conn
+	wmu  sync.Mutex
+	enc  *json.Encoder
+
+	mu      sync.Mutex
+	pending map[uint64]chan Response
+	err     error
+}
+
+func newClientConn(conn net.Conn) *clientConn {
+	cc := &clientConn{
+		conn:    conn,
+		enc:     json.NewEncoder(conn),
+		pending: make(map[uint64]chan Response),
+	}
+	go cc.readLoop()
+	return cc
+}
+
+func (cc *clientConn) readLoop() {
+	dec := json.NewDecoder(cc.conn)
+	for {
 		var resp Response
 		if err := dec.Decode(&resp); err != nil {
-			return err
+			cc.fail(err)
+			return
+		}
+		if resp.ID == 0 {
+			// Every request carries an ID, so the daemon does not
+			// understand them.
+			cc.fail(errLegacyDaemon)
+			return
 		}
-		if !resp.OK {
-			return errorsNew(resp.Error)
+		cc.mu.Lock()
+		ch := cc.pending[resp.ID]
+		delete(cc.pending, resp.ID)
+		cc.mu.Unlock()
+		if ch != nil {
+			ch <- resp
 		}
-		return nil
-	})
+	}
 }
 
-// Local helper to avoid importing fmt just for errors.
-func errorsNew(msg string) error { return &simpleError{s: msg} }
+// fail marks the connection broken, closes it and wakes all waiters.
+func (cc *clientConn) fail(err error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.err != nil {
+		return
+	}
+	cc.err = err
+	_ = cc.conn.Close()
+	for id, ch := range cc.pending {
+		close(ch)
+		delete(cc.pending, id)
+	}
+}
 
-type simpleError struct{ s string }
+func (cc *clientConn) broken() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.err != nil
+}
+
+// brokenErr wraps the reason the connection failed in errConnBroken.
+func (cc *clientConn) brokenErr() error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return fmt.Errorf("%w: %w", errConnBroken, cc.err)
+}
 
-func (e *simpleError) Error() string { return e.s }
+func (cc *clientConn) roundTrip(req Request, timeout time.Duration) (Response, error) {
+	ch := make(chan Response, 1)
+	cc.mu.Lock()
+	if cc.err != nil {
+		cc.mu.Unlock()
+		return Response{}, errConnBroken
+	}
+	cc.pending[req.ID] = ch
+	cc.mu.Unlock()
+
+	cc.wmu.Lock()
+	_ = cc.conn.SetWriteDeadline(time.Now().Add(timeout))
+	err := cc.enc.Encode(&req)
+	cc.wmu.Unlock()
+	if err != nil {
+		cc.fail(err)
+		return Response{}, errConnBroken
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return Response{}, cc.brokenErr()
+		}
+		return resp, nil
+	case <-timer.C:
+		// A response that never arrives means the daemon is stuck or the
+		// stream is out of sync; drop the connection rather than reuse it.
+		cc.fail(errors.New("cache: request timed out"))
+		return Response{}, errors.New("cache: request timed out")
+	}
+}
@@ -0,0 +1,373 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeDaemon speaks the cache protocol over a Unix socket, answering every
+// request in its own goroutine so replies can overtake each other.
+type fakeDaemon struct {
+	path  string
+	ln    net.Listener
+	dials atomic.Int32
+
+	// delay, when set, is how long to wait before answering a key.
+	delay func(key string) time.Duration
+	// drop, when set, closes the connection instead of answering.
+	drop func(req Request) bool
+	// minVersion and maxVersion are the protocol versions hello
+	// negotiates from. When forced is set, hello reports it regardless of
+	// what the client supports. Legacy daemons answer without IDs and
+	// reject hello.
+	minVersion, maxVersion int
+	forced                 int
+	legacy                 bool
+
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newFakeDaemon(t testing.TB) *fakeDaemon {
+	t.Helper()
+	// Socket paths are limited to about 100 bytes, which t.TempDir can
+	// exceed.
+	dir, err := os.MkdirTemp("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	d := &fakeDaemon{path: filepath.Join(dir, "s"), values: make(map[string][]byte), minVersion: MinProtocolVersion, maxVersion: ProtocolVersion}
+	d.ln, err = net.Listen("unix", d.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.ln.Close() })
+	go d.serve()
+	return d
+}
+
+func (d *fakeDaemon) serve() {
+	for {
+		conn, err := d.ln.Accept()
+		if err != nil {
+			return
+		}
+		d.dials.Add(1)
+		go d.handle(conn)
+	}
+}
+
+func (d *fakeDaemon) handle(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	var wmu sync.Mutex
+	for {
+		var req Request
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		if d.drop != nil && d.drop(req) {
+			return
+		}
+		go func() {
+			if d.delay != nil {
+				time.Sleep(d.delay(req.Key))
+			}
+			resp := d.answer(req)
+			if !d.legacy {
+				resp.ID = req.ID
+			}
+			wmu.Lock()
+			defer wmu.Unlock()
+			_ = enc.Encode(&resp)
+		}()
+	}
+}
+
+func (d *fakeDaemon) answer(req Request) Response {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch req.Op {
+	case "hello":
+		if d.legacy {
+			return Response{OK: false, Error: "unknown op"}
+		}
+		if d.forced != 0 {
+			return Response{OK: true, Version: d.forced}
+		}
+		v, err := negotiateVersion(req, d.minVersion, d.maxVersion)
+		if err != nil {
+			return ErrorResponse(err)
+		}
+		return Response{OK: true, Version: v}
+	case "get":
+		v, ok := d.values[req.Key]
+		if !ok {
+			return ErrorResponse(ErrNotFound)
+		}
+		return Response{OK: true, Value: v}
+	case "put":
+		d.values[req.Key] = req.Value
+		return Response{OK: true}
+	case "delete":
+		delete(d.values, req.Key)
+		return Response{OK: true}
+	}
+	return ErrorResponse(ErrUnknownOp)
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	d := newFakeDaemon(t)
+	c := NewClient(d.path)
+	defer c.Close()
+
+	if err := c.Put("k", []byte("v"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.Get("k")
+	if err != nil || string(v) != "v" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if err := c.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete err = %v, want ErrNotFound", err)
+	}
+	if _, err := c.Keys(""); !errors.Is(err, ErrUnknownOp) {
+		t.Fatalf("Keys err = %v, want ErrUnknownOp", err)
+	}
+	if got := c.ServerVersion(); got != ProtocolVersion {
+		t.Errorf("ServerVersion = %d, want %d", got, ProtocolVersion)
+	}
+}
+
+func TestClientNegotiatesWithNewerDaemon(t *testing.T) {
+	d := newFakeDaemon(t)
+	d.maxVersion = ProtocolVersion + 2
+	c := NewClient(d.path)
+	defer c.Close()
+	if _, err := c.Get("k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get err = %v, want ErrNotFound", err)
+	}
+	if got := c.ServerVersion(); got != ProtocolVersion {
+		t.Errorf("ServerVersion = %d, want %d", got, ProtocolVersion)
+	}
+}
+
+func TestClientRefusesOtherProtocolVersions(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		configure func(*fakeDaemon)
+	}{
+		{name: "legacy", configure: func(d *fakeDaemon) { d.legacy = true }},
+		{name: "no common version", configure: func(d *fakeDaemon) { d.minVersion, d.maxVersion = ProtocolVersion+1, ProtocolVersion+2 }},
+		{name: "unsupported answer", configure: func(d *fakeDaemon) { d.forced = ProtocolVersion + 1 }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newFakeDaemon(t)
+			tc.configure(d)
+			c := NewClient(d.path)
+			defer c.Close()
+
+			start := time.Now()
+			_, err := c.Get("k")
+			if err == nil || !strings.Contains(err.Error(), "restart it") {
+				t.Fatalf("Get err = %v, want a protocol version error", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("refusing the daemon took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestClientPing(t *testing.T) {
+	d := newFakeDaemon(t)
+	c := NewClient(d.path)
+	defer c.Close()
+	if err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.ServerVersion(); got != ProtocolVersion {
+		t.Errorf("ServerVersion = %d, want %d", got, ProtocolVersion)
+	}
+
+	// A socket that accepts connections is not enough.
+	legacy := newFakeDaemon(t)
+	legacy.legacy = true
+	c = NewClient(legacy.path)
+	defer c.Close()
+	if err := c.Ping(); err == nil {
+		t.Error("Ping succeeded against a daemon that failed the handshake")
+	}
+}
+
+func TestClientOutOfOrderReplies(t *testing.T) {
+	d := newFakeDaemon(t)
+	d.delay = func(key string) time.Duration {
+		if key == "slow" {
+			return 100 * time.Millisecond
+		}
+		return 0
+	}
+	d.values["slow"] = []byte("slow")
+	d.values["fast"] = []byte("fast")
+	c := NewClient(d.path)
+	defer c.Close()
+
+	// Open every pooled connection so both requests share the pool.
+	for range clientConns {
+		if _, err := c.Get("fast"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	slowDone := make(chan time.Time, 1)
+	go func() {
+		v, err := c.Get("slow")
+		if err != nil || string(v) != "slow" {
+			t.Errorf("Get(slow) = %q, %v", v, err)
+		}
+		slowDone <- time.Now()
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for range 4 * clientConns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.Get("fast")
+			if err != nil || string(v) != "fast" {
+				t.Errorf("Get(fast) = %q, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	fastDone := time.Now()
+	if slow := <-slowDone; slow.Before(fastDone) {
+		t.Error("fast requests waited for the slow one")
+	}
+	if n := d.dials.Load(); n != clientConns {
+		t.Errorf("client dialed %d connections, want %d", n, clientConns)
+	}
+}
+
+func TestClientSlowHandshakeBlocksOnlyItsSlot(t *testing.T) {
+	d := newFakeDaemon(t)
+	d.values["k"] = []byte("v")
+	var hellos atomic.Int32
+	d.delay = func(key string) time.Duration {
+		// Only hello requests have no key; stall the first one.
+		if key == "" && hellos.Add(1) == 1 {
+			return 300 * time.Millisecond
+		}
+		return 0
+	}
+	c := NewClient(d.path)
+	defer c.Close()
+
+	go c.Get("k")
+	time.Sleep(20 * time.Millisecond)
+	start := time.Now()
+	if _, err := c.Get("k"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("request on another slot waited %v for the stalled handshake", elapsed)
+	}
+}
+
+func TestClientRetriesWhenConnectionDrops(t *testing.T) {
+	d := newFakeDaemon(t)
+	d.values["k"] = []byte("v")
+	var dropped atomic.Bool
+	d.drop = func(req Request) bool {
+		// Kill the connection with the first get in flight.
+		return req.Op == "get" && dropped.CompareAndSwap(false, true)
+	}
+	c := NewClient(d.path)
+	defer c.Close()
+
+	v, err := c.Get("k")
+	if err != nil || string(v) != "v" {
+		t.Fatalf("Get = %q, %v; want the retry to succeed", v, err)
+	}
+	if !dropped.Load() {
+		t.Fatal("the connection was never dropped")
+	}
+	if n := d.dials.Load(); n != 2 {
+		t.Errorf("client dialed %d connections, want 2", n)
+	}
+
+	// The broken connection is replaced rather than reused.
+	for range 2 * clientConns {
+		if _, err := c.Get("k"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClientFailsWhenDaemonIsGone(t *testing.T) {
+	d := newFakeDaemon(t)
+	c := NewClient(d.path)
+	defer c.Close()
+	if err := c.Put("k", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+	d.ln.Close()
+	os.Remove(d.path)
+	d.drop = func(Request) bool { return true }
+
+	if _, err := c.Get("k"); err == nil {
+		t.Fatal("Get succeeded with the daemon gone")
+	}
+}
+
+func BenchmarkClient(b *testing.B) {
+	d := newFakeDaemon(b)
+	d.values["k"] = []byte("value")
+
+	b.Run("pooled", func(b *testing.B) {
+		c := NewClient(d.path)
+		defer c.Close()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := c.Get("k"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+
+	// dial-per-request is how the client worked before pooling: one
+	// connection and one request each time.
+	b.Run("dial-per-request", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				conn, err := net.DialTimeout("unix", d.path, dialTimeout)
+				if err != nil {
+					b.Fatal(err)
+				}
+				var resp Response
+				if err := json.NewEncoder(conn).Encode(&Request{Op: "get", Key: "k"}); err == nil {
+					err = json.NewDecoder(conn).Decode(&resp)
+				}
+				conn.Close()
+				if err != nil || !resp.OK {
+					b.Fatal(err, resp.Error)
+				}
+			}
+		})
+	})
+}
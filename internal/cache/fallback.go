@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// FallbackOptions configures a Fallback.
+type FallbackOptions struct {
+	// ProbeInterval is how often the primary is dialed while unavailable.
+	// It defaults to 30 seconds.
+	ProbeInterval time.Duration
+	// Logf, when set, receives warnings about switching between the
+	// primary and the in-memory cache.
+	Logf func(format string, args ...any)
+	// Primary, when set, is an already connected primary. The Fallback
+	// starts promoted to it and does not probe.
+	Primary KV
+}
+
+// Fallback is a KV that serves from an in-memory cache until the primary
+// (normally the cache daemon) becomes reachable, then promotes to it. While
+// promoted, operations that fail on the primary with anything other than a
+// cache miss are served from memory instead, so a dying daemon degrades
+// caching rather than breaking callers.
+//
+// Entries only ever stored in memory are not copied to the primary. Once
+// the primary is promoted or answers again, reads go to it alone, so what
+// was cached during an outage misses and is fetched again.
+type Fallback struct {
+	connect func() (KV, error)
+	memory  *Memory
+	opts    FallbackOptions
+
+	mu      sync.RWMutex
+	primary KV
+	failing bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewFallback returns a Fallback that calls connect in the background until
+// it succeeds, unless opts.Primary is already connected. memory serves all
+// operations in the meantime.
+func NewFallback(connect func() (KV, error), memory *Memory, opts FallbackOptions) *Fallback {
+	if opts.ProbeInterval <= 0 {
+		opts.ProbeInterval = 30 * time.Second
+	}
+	f := &Fallback{
+		connect: connect,
+		memory:  memory,
+		opts:    opts,
+		primary: opts.Primary,
+		stop:    make(chan struct{}),
+	}
+	if f.primary == nil {
+		go f.probe()
+	}
+	return f
+}
+
+// Close stops probing for the primary.
+func (f *Fallback) Close() error {
+	f.stopOnce.Do(func() { close(f.stop) })
+	return nil
+}
+
+func (f *Fallback) probe() {
+	t := time.NewTicker(f.opts.ProbeInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-t.C:
+		}
+		kv, err := f.connect()
+		if err != nil {
+			continue
+		}
+		f.mu.Lock()
+		f.primary = kv
+		f.mu.Unlock()
+		f.logf("Cache daemon is reachable, switching from the in-memory cache")
+		return
+	}
+}
+
+func (f *Fallback) logf(format string, args ...any) {
+	if f.opts.Logf != nil {
+		f.opts.Logf(format, args...)
+	}
+}
+
+func (f *Fallback) current() KV {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.primary
+}
+
+// healthy records whether the last primary operation succeeded, logging
+// transitions only so a down daemon does not flood the log.
+func (f *Fallback) healthy(err error) {
+	failing := err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrExpired)
+	f.mu.Lock()
+	changed := f.failing != failing
+	f.failing = failing
+	f.mu.Unlock()
+	if !changed {
+		return
+	}
+	if failing {
+		f.logf("Cache daemon request failed, using the in-memory cache: %v", err)
+	} else {
+		f.logf("Cache daemon recovered")
+	}
+}
+
+func (f *Fallback) Get(key string) ([]byte, error) {
+	if p := f.current(); p != nil {
+		v, err := p.Get(key)
+		f.healthy(err)
+		if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrExpired) {
+			return v, err
+		}
+	}
+	return f.memory.Get(key)
+}
+
+func (f *Fallback) Put(key string, value []byte, ttl time.Duration) error {
+	if p := f.current(); p != nil {
+		err := p.Put(key, value, ttl)
+		f.healthy(err)
+		if err == nil {
+			return nil
+		}
+	}
+	return f.memory.Put(key, value, ttl)
+}
+
+func (f *Fallback) Delete(key string) error {
+	if p := f.current(); p != nil {
+		err := p.Delete(key)
+		f.healthy(err)
+	}
+	return f.memory.Delete(key)
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyKV is a primary that fails every operation while down is set.
+type flakyKV struct {
+	*Memory
+	down atomic.Bool
+}
+
+var errDown = errors.New("daemon down")
+
+func (k *flakyKV) Get(key string) ([]byte, error) {
+	if k.down.Load() {
+		return nil, errDown
+	}
+	return k.Memory.Get(key)
+}
+
+func (k *flakyKV) Put(key string, value []byte, ttl time.Duration) error {
+	if k.down.Load() {
+		return errDown
+	}
+	return k.Memory.Put(key, value, ttl)
+}
+
+func TestFallbackStartsPromoted(t *testing.T) {
+	primary := &flakyKV{Memory: NewMemory(0, 0)}
+	f := NewFallback(func() (KV, error) {
+		t.Error("a connected primary was probed")
+		return nil, errDown
+	}, NewMemory(0, 0), FallbackOptions{Primary: primary, ProbeInterval: time.Millisecond})
+	defer f.Close()
+
+	if err := f.Put("a", []byte("1"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := primary.Memory.Get("a"); err != nil || string(v) != "1" {
+		t.Fatalf("primary has %q, %v; want the value written through", v, err)
+	}
+
+	// The daemon dies: operations are served from memory.
+	primary.down.Store(true)
+	if err := f.Put("b", []byte("2"), 0); err != nil {
+		t.Fatalf("Put with the primary down: %v", err)
+	}
+	if v, err := f.Get("b"); err != nil || string(v) != "2" {
+		t.Fatalf("Get with the primary down = %q, %v", v, err)
+	}
+
+	// It comes back: the primary is used again.
+	primary.down.Store(false)
+	if v, err := f.Get("a"); err != nil || string(v) != "1" {
+		t.Fatalf("Get after recovery = %q, %v", v, err)
+	}
+	// Give a stray probe the chance to report itself.
+	time.Sleep(5 * time.Millisecond)
+}
+
+func TestFallbackPromotesWhenReachable(t *testing.T) {
+	primary := NewMemory(0, 0)
+	var attempts atomic.Int32
+	f := NewFallback(func() (KV, error) {
+		if attempts.Add(1) < 3 {
+			return nil, errDown
+		}
+		return primary, nil
+	}, NewMemory(0, 0), FallbackOptions{ProbeInterval: time.Millisecond})
+	defer f.Close()
+
+	if err := f.Put("early", []byte("m"), 0); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for f.current() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("never promoted to the primary")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := f.Put("late", []byte("p"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := primary.Get("late"); err != nil {
+		t.Errorf("write after promotion did not reach the primary: %v", err)
+	}
+	if _, err := primary.Get("early"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("write before promotion reached the primary: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process KV with TTL expiry and LRU eviction. It is used
+// when the cache daemon is unavailable and is safe for concurrent use by
+// multiple goroutines.
+type Memory struct {
+	maxEntries int
+	defaultTTL time.Duration
+
+	mu    sync.Mutex
+	order *list.List // front is most recently used
+	items map[string]*list.Element
+}
+
+type memoryItem struct {
+	key       string
+	value     []byte
+	expiresAt time.Time // zero means no expiry
+}
+
+// NewMemory returns a Memory holding at most maxEntries items (unlimited
+// when <= 0). defaultTTL is used when Put is called with ttl <= 0; if it is
+// also <= 0, items never expire.
+func NewMemory(maxEntries int, defaultTTL time.Duration) *Memory {
+	return &Memory{
+		maxEntries: maxEntries,
+		defaultTTL: defaultTTL,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (m *Memory) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.items[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	it := el.Value.(*memoryItem)
+	if !it.expiresAt.IsZero() && time.Now().After(it.expiresAt) {
+		m.order.Remove(el)
+		delete(m.items, key)
+		return nil, ErrExpired
+	}
+	m.order.MoveToFront(el)
+	return append([]byte(nil), it.value...), nil
+}
+
+func (m *Memory) Put(key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = m.defaultTTL
+	}
+	it := &memoryItem{key: key, value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		it.expiresAt = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[key]; ok {
+		el.Value = it
+		m.order.MoveToFront(el)
+		return nil
+	}
+	m.items[key] = m.order.PushFront(it)
+	for m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.items, oldest.Value.(*memoryItem).key)
+	}
+	return nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[key]; ok {
+		m.order.Remove(el)
+		delete(m.items, key)
+	}
+	return nil
+}
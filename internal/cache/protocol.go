@@ -1,17 +1,147 @@
 package cache
 
+import (
+	"errors"
+	"fmt"
+)
+
 // Simple JSON protocol for cache daemon over a Unix domain socket.
-// One request -> one response using json.Encoder/Decoder per connection.
+// Requests and responses are newline-delimited JSON values on a long-lived
+// connection. A request with a non-zero ID may be answered out of order and
+// its response carries the same ID; requests without an ID are answered in
+// order, one at a time.
+//
+// Clients open each connection with a "hello" request carrying the range of
+// protocol versions they support. The daemon answers with the highest
+// version both sides support, or CodeUnsupportedVersion when there is none,
+// so either side can be upgraded first as long as the ranges overlap.
+// Daemons that predate the handshake answer without an ID and are refused.
+
+const (
+	// ProtocolVersion is the newest version of the wire protocol spoken by
+	// this package. It is incremented when requests or responses change
+	// meaning.
+	ProtocolVersion = 1
+	// MinProtocolVersion is the oldest version this package still speaks.
+	MinProtocolVersion = 1
+)
 
 type Request struct {
-	Op         string `json:"op"` // "get" | "put" | "delete"
+	ID uint64 `json:"id,omitempty"`
+	// Op is "hello", "get", "put" or "delete", or one of the admin ops
+	// "keys" and "delete_prefix" (Key is the prefix), "purge_expired",
+	// "stats" and "compact".
+	Op         string `json:"op"`
 	Key        string `json:"key"`
 	Value      []byte `json:"value,omitempty"`
 	TTLSeconds int64  `json:"ttl_seconds,omitempty"`
+	// Version and MinVersion are the newest and oldest protocol versions
+	// the client supports, sent with "hello". A zero MinVersion means the
+	// client supports only Version.
+	Version    int `json:"version,omitempty"`
+	MinVersion int `json:"min_version,omitempty"`
 }
 
 type Response struct {
-	OK    bool   `json:"ok"`
-	Value []byte `json:"value,omitempty"`
-	Error string `json:"error,omitempty"`
+	ID    uint64    `json:"id,omitempty"`
+	OK    bool      `json:"ok"`
+	Value []byte    `json:"value,omitempty"`
+	Code  ErrorCode `json:"code,omitempty"`
+	Error string    `json:"error,omitempty"`
+	// Version is the protocol version negotiated by "hello".
+	Version int `json:"version,omitempty"`
+	// Entries, Count and Stats carry the results of admin ops.
+	Entries []EntryInfo `json:"entries,omitempty"`
+	Count   int         `json:"count,omitempty"`
+	Stats   *Stats      `json:"stats,omitempty"`
+}
+
+// ErrorCode classifies a failed Response. Codes are stable across protocol
+// versions; Error holds a human-readable message that may change.
+type ErrorCode string
+
+const (
+	CodeNotFound ErrorCode = "not_found"
+	CodeExpired  ErrorCode = "expired"
+	// CodeUnknownOp is returned for ops the daemon does not implement.
+	CodeUnknownOp ErrorCode = "unknown_op"
+	// CodeUnsupportedVersion is returned by "hello" when the client and
+	// daemon have no protocol version in common.
+	CodeUnsupportedVersion ErrorCode = "unsupported_version"
+	CodeInternal           ErrorCode = "internal"
+)
+
+var (
+	// ErrUnknownOp is returned when the daemon does not implement an op.
+	ErrUnknownOp = errors.New("cache: unknown op")
+	// ErrUnsupportedVersion is returned when the client and daemon have no
+	// protocol version in common.
+	ErrUnsupportedVersion = errors.New("cache: unsupported protocol version")
+)
+
+// NegotiateVersion returns the protocol version a daemon built from this
+// package uses for a "hello" request: the newest one supported by both
+// sides.
+func NegotiateVersion(req Request) (int, error) {
+	return negotiateVersion(req, MinProtocolVersion, ProtocolVersion)
+}
+
+func negotiateVersion(req Request, lo, hi int) (int, error) {
+	clientLo := req.MinVersion
+	if clientLo == 0 {
+		clientLo = req.Version
+	}
+	v := min(req.Version, hi)
+	if v < max(clientLo, lo) {
+		return 0, fmt.Errorf("%w: client speaks versions %d to %d, daemon %d to %d", ErrUnsupportedVersion, clientLo, req.Version, lo, hi)
+	}
+	return v, nil
+}
+
+// RemoteError is a failure reported by the cache daemon that has no
+// dedicated sentinel error.
+type RemoteError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *RemoteError) Error() string { return e.Message }
+
+// ErrorResponse builds the failed Response for err.
+func ErrorResponse(err error) Response {
+	code := CodeInternal
+	switch {
+	case errors.Is(err, ErrNotFound):
+		code = CodeNotFound
+	case errors.Is(err, ErrExpired):
+		code = CodeExpired
+	case errors.Is(err, ErrUnknownOp):
+		code = CodeUnknownOp
+	case errors.Is(err, ErrUnsupportedVersion):
+		code = CodeUnsupportedVersion
+	}
+	return Response{OK: false, Code: code, Error: err.Error()}
+}
+
+// Err converts a failed Response back into an error. Known codes map to
+// their sentinel errors so callers can use errors.Is.
+func (r Response) Err() error {
+	if r.OK {
+		return nil
+	}
+	code := r.Code
+	if code == "" {
+		code = CodeInternal
+	}
+	switch code {
+	case CodeNotFound:
+		return ErrNotFound
+	case CodeExpired:
+		return ErrExpired
+	case CodeUnknownOp:
+		return ErrUnknownOp
+	case CodeUnsupportedVersion:
+		return fmt.Errorf("%w (%s)", ErrUnsupportedVersion, r.Error)
+	}
+	return &RemoteError{Code: code, Message: r.Error}
 }
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		req                Request
+		daemonLo, daemonHi int
+		want               int
+	}{
+		{name: "same", req: Request{Version: 1, MinVersion: 1}, daemonLo: 1, daemonHi: 1, want: 1},
+		{name: "newer daemon", req: Request{Version: 2, MinVersion: 1}, daemonLo: 1, daemonHi: 4, want: 2},
+		{name: "newer client", req: Request{Version: 4, MinVersion: 2}, daemonLo: 1, daemonHi: 3, want: 3},
+		{name: "no minimum", req: Request{Version: 2}, daemonLo: 1, daemonHi: 3, want: 2},
+		{name: "client too old", req: Request{Version: 1}, daemonLo: 2, daemonHi: 3},
+		{name: "client too new", req: Request{Version: 5, MinVersion: 4}, daemonLo: 1, daemonHi: 3},
+		{name: "no version", req: Request{}, daemonLo: 1, daemonHi: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := negotiateVersion(tc.req, tc.daemonLo, tc.daemonHi)
+			if tc.want == 0 {
+				if !errors.Is(err, ErrUnsupportedVersion) {
+					t.Fatalf("negotiateVersion = %d, %v; want ErrUnsupportedVersion", got, err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("negotiateVersion = %d, %v; want %d", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestUnsupportedVersionSurvivesTheWire(t *testing.T) {
+	_, err := negotiateVersion(Request{Version: 9, MinVersion: 9}, 1, 1)
+	if got := ErrorResponse(err).Err(); !errors.Is(got, ErrUnsupportedVersion) {
+		t.Fatalf("round-tripped error = %v, want ErrUnsupportedVersion", got)
+	}
+}
@@ -0,0 +1,296 @@
+// Package config loads the settings shared by the web-mcp server and the
+// cache daemon from a YAML file, with environment variables overriding
+// values from the file.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPath names the environment variable holding the config file path.
+const EnvPath = "WEB_MCP_CONFIG"
+
+// Config holds every setting of the server and the cache daemon. Durations
+// are written as Go duration strings such as "15m" or "24h".
+type Config struct {
+	Server  Server  `yaml:"server"`
+	Fetch   Fetch   `yaml:"fetch"`
+	Search  Search  `yaml:"search"`
+	Domains Domains `yaml:"domains"`
+	Cache   Cache   `yaml:"cache"`
+	Auth    Auth    `yaml:"auth"`
+	Log     Log     `yaml:"log"`
+}
+
+type Server struct {
+	// Transport is "stdio", "http" or "sse".
+	Transport string `yaml:"transport"`
+	// Addr is the listen address of the HTTP transports.
+	Addr string `yaml:"addr"`
+}
+
+type Fetch struct {
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxResponseSize and MaxPDFSize are in bytes.
+	MaxResponseSize int           `yaml:"max_response_size"`
+	MaxPDFSize      int           `yaml:"max_pdf_size"`
+	HostDelay       time.Duration `yaml:"host_delay"`
+	HostParallelism int           `yaml:"host_parallelism"`
+	// DefaultTTL, MinTTL and MaxTTL control how long fetched pages are
+	// cached; see web.FetcherOptions.
+	DefaultTTL time.Duration `yaml:"default_ttl"`
+	MinTTL     time.Duration `yaml:"min_ttl"`
+	MaxTTL     time.Duration `yaml:"max_ttl"`
+	// AllowAddresses lists private CIDR ranges, IP addresses and hostnames
+	// that may be fetched.
+	AllowAddresses []string `yaml:"allow_addresses"`
+}
+
+type Search struct {
+	// Backend is "duckduckgo", "searxng", "brave" or "bing".
+	Backend  string        `yaml:"backend"`
+	Endpoint string        `yaml:"endpoint"`
+	APIKey   string        `yaml:"api_key"`
+	TTL      time.Duration `yaml:"ttl"`
+}
+
+// Domains is the host policy applied to fetches and search results.
+type Domains struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+type Cache struct {
+	Socket          string        `yaml:"socket"`
+	DB              string        `yaml:"db"`
+	DefaultTTL      time.Duration `yaml:"default_ttl"`
+	SweepInterval   time.Duration `yaml:"sweep_interval"`
+	CompactInterval time.Duration `yaml:"compact_interval"`
+	MaxEntries      int           `yaml:"max_entries"`
+	MaxMB           int           `yaml:"max_mb"`
+	// MemoryEntries bounds the in-memory cache used while the daemon is
+	// unavailable.
+	MemoryEntries int `yaml:"memory_entries"`
+}
+
+type Auth struct {
+	Keys []Key `yaml:"keys"`
+	// RatePerMinute, DailyFetchQuota and DailySearchQuota apply to each
+	// client. Zero disables the limit.
+	RatePerMinute    int `yaml:"rate_per_minute"`
+	DailyFetchQuota  int `yaml:"daily_fetch_quota"`
+	DailySearchQuota int `yaml:"daily_search_quota"`
+}
+
+type Key struct {
+	Client string `yaml:"client"`
+	Token  string `yaml:"token"`
+}
+
+type Log struct {
+	// Path is the log file. Empty logs next to the executable.
+	Path string `yaml:"path"`
+}
+
+// Default returns the built-in settings.
+func Default() Config {
+	dir := defaultDir()
+	return Config{
+		Server: Server{Transport: "stdio", Addr: ":8080"},
+		Fetch: Fetch{
+			Timeout:         20 * time.Second,
+			MaxResponseSize: 1 * 1024 * 1024,
+			MaxPDFSize:      10 * 1024 * 1024,
+			HostDelay:       1 * time.Second,
+			HostParallelism: 1,
+			DefaultTTL:      15 * time.Minute,
+			MinTTL:          1 * time.Minute,
+			MaxTTL:          24 * time.Hour,
+		},
+		Search: Search{Backend: "duckduckgo", TTL: 5 * time.Minute},
+		Cache: Cache{
+			Socket:          filepath.Join(dir, "cache.sock"),
+			DB:              filepath.Join(dir, "cache.bbolt"),
+			DefaultTTL:      15 * time.Minute,
+			SweepInterval:   1 * time.Minute,
+			CompactInterval: 1 * time.Hour,
+			MaxMB:           256,
+			MemoryEntries:   1000,
+		},
+	}
+}
+
+func defaultDir() string {
+	home, _ := os.UserHomeDir()
+	if home == "" {
+		home = "."
+	}
+	return filepath.Join(home, ".cache", "web-mcp")
+}
+
+// Path returns the config file to load: flagValue when set, then
+// WEB_MCP_CONFIG, then ~/.config/web-mcp/config.yaml if it exists. It
+// returns "" when there is no config file.
+func Path(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if p := os.Getenv(EnvPath); p != "" {
+		return p
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		p := filepath.Join(dir, "web-mcp", "config.yaml")
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// Load reads the config file at path on top of the defaults, applies
+// environment overrides and validates the result. An empty path skips the
+// file.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: %w", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return Config{}, fmt.Errorf("config: %s: %w", path, err)
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides settings from the WEB_MCP_* environment variables.
+func (c *Config) applyEnv() error {
+	str := func(name string, dst *string) {
+		if v := os.Getenv(name); v != "" {
+			*dst = v
+		}
+	}
+	list := func(name string, dst *[]string) {
+		if v := os.Getenv(name); v != "" {
+			*dst = splitList(v)
+		}
+	}
+	var errs []error
+	num := func(name string, dst *int) {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid integer %q", name, v))
+				return
+			}
+			*dst = n
+		}
+	}
+
+	str("WEB_MCP_TRANSPORT", &c.Server.Transport)
+	str("WEB_MCP_ADDR", &c.Server.Addr)
+	list("WEB_MCP_FETCH_ALLOW", &c.Fetch.AllowAddresses)
+	str("WEB_MCP_SEARCH_BACKEND", &c.Search.Backend)
+	str("WEB_MCP_SEARCH_ENDPOINT", &c.Search.Endpoint)
+	str("WEB_MCP_SEARCH_API_KEY", &c.Search.APIKey)
+	list("WEB_MCP_ALLOW_DOMAINS", &c.Domains.Allow)
+	list("WEB_MCP_DENY_DOMAINS", &c.Domains.Deny)
+	str("WEB_MCP_CACHE_SOCK", &c.Cache.Socket)
+	str("WEB_MCP_CACHE_DB", &c.Cache.DB)
+	num("WEB_MCP_CACHE_MAX_ENTRIES", &c.Cache.MaxEntries)
+	num("WEB_MCP_CACHE_MAX_MB", &c.Cache.MaxMB)
+	num("WEB_MCP_RATE_LIMIT", &c.Auth.RatePerMinute)
+	num("WEB_MCP_DAILY_FETCH_QUOTA", &c.Auth.DailyFetchQuota)
+	num("WEB_MCP_DAILY_SEARCH_QUOTA", &c.Auth.DailySearchQuota)
+	str("WEB_MCP_LOG", &c.Log.Path)
+	if v := os.Getenv("WEB_MCP_API_KEYS"); v != "" {
+		keys, err := parseKeys(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("WEB_MCP_API_KEYS: %w", err))
+		}
+		c.Auth.Keys = keys
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports settings that are out of range or inconsistent.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	switch c.Server.Transport {
+	case "stdio", "http", "sse":
+	default:
+		errs = append(errs, fmt.Errorf("server.transport: %q must be stdio, http or sse", c.Server.Transport))
+	}
+	check(c.Server.Transport == "stdio" || c.Server.Addr != "", "server.addr: required for the %s transport", c.Server.Transport)
+
+	check(c.Fetch.Timeout > 0, "fetch.timeout: must be positive")
+	check(c.Fetch.MaxResponseSize > 0, "fetch.max_response_size: must be positive")
+	check(c.Fetch.MaxPDFSize > 0, "fetch.max_pdf_size: must be positive")
+	check(c.Fetch.HostDelay > 0, "fetch.host_delay: must be positive")
+	check(c.Fetch.HostParallelism > 0, "fetch.host_parallelism: must be positive")
+	check(c.Fetch.DefaultTTL >= 0 && c.Fetch.MinTTL >= 0 && c.Fetch.MaxTTL >= 0, "fetch: TTLs must not be negative")
+	check(c.Fetch.MaxTTL == 0 || c.Fetch.MinTTL <= c.Fetch.MaxTTL, "fetch.min_ttl: %s exceeds max_ttl %s", c.Fetch.MinTTL, c.Fetch.MaxTTL)
+
+	check(c.Search.TTL >= 0, "search.ttl: must not be negative")
+
+	check(c.Cache.Socket != "", "cache.socket: required")
+	check(c.Cache.DB != "", "cache.db: required")
+	check(c.Cache.DefaultTTL >= 0 && c.Cache.SweepInterval >= 0 && c.Cache.CompactInterval >= 0, "cache: durations must not be negative")
+	check(c.Cache.MaxEntries >= 0, "cache.max_entries: must not be negative")
+	check(c.Cache.MaxMB >= 0, "cache.max_mb: must not be negative")
+	check(c.Cache.MemoryEntries >= 0, "cache.memory_entries: must not be negative")
+
+	for i, k := range c.Auth.Keys {
+		check(k.Client != "" && k.Token != "", "auth.keys[%d]: client and token are required", i)
+	}
+	check(c.Auth.RatePerMinute >= 0 && c.Auth.DailyFetchQuota >= 0 && c.Auth.DailySearchQuota >= 0, "auth: limits must not be negative")
+	return errors.Join(errs...)
+}
+
+// splitList splits a comma-separated value, dropping blanks.
+func splitList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// parseKeys parses a comma-separated list of "client:token" pairs.
+func parseKeys(v string) ([]Key, error) {
+	var keys []Key
+	for _, part := range splitList(v) {
+		client, token, ok := strings.Cut(part, ":")
+		if !ok || client == "" || token == "" {
+			return nil, fmt.Errorf("invalid entry %q: expected client:token", part)
+		}
+		keys = append(keys, Key{Client: client, Token: token})
+	}
+	return keys, nil
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Load(\"\") = %+v, want the defaults", cfg)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  transport: http
+  addr: "127.0.0.1:9000"
+fetch:
+  host_delay: 250ms
+  allow_addresses: [10.1.0.0/16]
+search:
+  backend: searxng
+  endpoint: https://searx.example.org
+auth:
+  keys:
+    - client: alice
+      token: secret
+  rate_per_minute: 30
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Default()
+	want.Server = Server{Transport: "http", Addr: "127.0.0.1:9000"}
+	want.Fetch.HostDelay = 250 * time.Millisecond
+	want.Fetch.AllowAddresses = []string{"10.1.0.0/16"}
+	want.Search.Backend = "searxng"
+	want.Search.Endpoint = "https://searx.example.org"
+	want.Auth.Keys = []Key{{Client: "alice", Token: "secret"}}
+	want.Auth.RatePerMinute = 30
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Load = %+v\nwant %+v", cfg, want)
+	}
+}
+
+func TestLoadEmptyFile(t *testing.T) {
+	cfg, err := Load(writeConfig(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("empty file: got %+v, want the defaults", cfg)
+	}
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	_, err := Load(writeConfig(t, "fetch:\n  host_dealy: 2s\n"))
+	if err == nil || !strings.Contains(err.Error(), "host_dealy") {
+		t.Fatalf("err = %v, want it to name the unknown field", err)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("loading a missing file succeeded")
+	}
+}
+
+func TestEnvironmentOverridesFile(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  transport: sse
+search:
+  backend: brave
+  api_key: from-file
+cache:
+  max_mb: 64
+`)
+	t.Setenv("WEB_MCP_TRANSPORT", "http")
+	t.Setenv("WEB_MCP_SEARCH_API_KEY", "from-env")
+	t.Setenv("WEB_MCP_CACHE_MAX_MB", "512")
+	t.Setenv("WEB_MCP_DENY_DOMAINS", " ads.example.com, ,tracker.example ")
+	t.Setenv("WEB_MCP_API_KEYS", "alice:one,bob:two")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Transport != "http" || cfg.Search.Backend != "brave" || cfg.Search.APIKey != "from-env" || cfg.Cache.MaxMB != 512 {
+		t.Errorf("got transport %q, backend %q, api key %q, max_mb %d", cfg.Server.Transport, cfg.Search.Backend, cfg.Search.APIKey, cfg.Cache.MaxMB)
+	}
+	if want := []string{"ads.example.com", "tracker.example"}; !reflect.DeepEqual(cfg.Domains.Deny, want) {
+		t.Errorf("deny = %q, want %q", cfg.Domains.Deny, want)
+	}
+	if want := []Key{{"alice", "one"}, {"bob", "two"}}; !reflect.DeepEqual(cfg.Auth.Keys, want) {
+		t.Errorf("keys = %+v, want %+v", cfg.Auth.Keys, want)
+	}
+}
+
+func TestInvalidEnvironment(t *testing.T) {
+	for _, tc := range []struct{ name, value, want string }{
+		{"WEB_MCP_CACHE_MAX_ENTRIES", "many", `WEB_MCP_CACHE_MAX_ENTRIES: invalid integer "many"`},
+		{"WEB_MCP_API_KEYS", "alice", `WEB_MCP_API_KEYS: invalid entry "alice"`},
+		{"WEB_MCP_API_KEYS", "alice:", `WEB_MCP_API_KEYS: invalid entry "alice:"`},
+	} {
+		t.Run(tc.name+"="+tc.value, func(t *testing.T) {
+			t.Setenv(tc.name, tc.value)
+			_, err := Load("")
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		want   string
+		modify func(*Config)
+	}{
+		{"server.transport", func(c *Config) { c.Server.Transport = "grpc" }},
+		{"server.addr", func(c *Config) { c.Server.Transport, c.Server.Addr = "http", "" }},
+		{"fetch.timeout", func(c *Config) { c.Fetch.Timeout = 0 }},
+		{"fetch.max_response_size", func(c *Config) { c.Fetch.MaxResponseSize = 0 }},
+		{"fetch.max_pdf_size", func(c *Config) { c.Fetch.MaxPDFSize = -1 }},
+		{"fetch.host_delay", func(c *Config) { c.Fetch.HostDelay = 0 }},
+		{"fetch.host_delay", func(c *Config) { c.Fetch.HostDelay = -time.Second }},
+		{"fetch.host_parallelism", func(c *Config) { c.Fetch.HostParallelism = 0 }},
+		{"fetch: TTLs", func(c *Config) { c.Fetch.DefaultTTL = -time.Minute }},
+		{"fetch.min_ttl", func(c *Config) { c.Fetch.MinTTL, c.Fetch.MaxTTL = time.Hour, time.Minute }},
+		{"search.ttl", func(c *Config) { c.Search.TTL = -time.Minute }},
+		{"cache.socket", func(c *Config) { c.Cache.Socket = "" }},
+		{"cache.db", func(c *Config) { c.Cache.DB = "" }},
+		{"cache: durations", func(c *Config) { c.Cache.SweepInterval = -time.Minute }},
+		{"cache.max_entries", func(c *Config) { c.Cache.MaxEntries = -1 }},
+		{"cache.max_mb", func(c *Config) { c.Cache.MaxMB = -1 }},
+		{"cache.memory_entries", func(c *Config) { c.Cache.MemoryEntries = -1 }},
+		{"auth.keys[1]", func(c *Config) { c.Auth.Keys = []Key{{"alice", "one"}, {"bob", ""}} }},
+		{"auth: limits", func(c *Config) { c.Auth.DailySearchQuota = -1 }},
+	} {
+		t.Run(tc.want, func(t *testing.T) {
+			cfg := Default()
+			tc.modify(&cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Validate = %v, want an error about %s", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateAcceptsZeroLimits(t *testing.T) {
+	cfg := Default()
+	cfg.Fetch.MinTTL, cfg.Fetch.MaxTTL = time.Hour, 0
+	cfg.Cache.MaxMB, cfg.Cache.SweepInterval, cfg.Cache.CompactInterval = 0, 0, 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
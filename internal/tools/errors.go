@@ -0,0 +1,34 @@
+package tools
+
+import (
+	"errors"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	web "github.com/leonardcser/web-mcp/internal/web"
+)
+
+// errorResult converts err into a tool error. Policy rejections and
+// selectors matching nothing also carry structured content so clients can
+// tell them apart from fetch failures.
+func errorResult(err error) *mcp.CallToolResult {
+	var denied *web.PolicyError
+	if errors.As(err, &denied) {
+		res := mcp.NewToolResultStructured(struct {
+			Error string `json:"error"`
+			*web.PolicyError
+		}{Error: "policy_denied", PolicyError: denied}, denied.Error())
+		res.IsError = true
+		return res
+	}
+	var missed *web.SelectorError
+	if errors.As(err, &missed) {
+		res := mcp.NewToolResultStructured(struct {
+			Error string `json:"error"`
+			*web.SelectorError
+		}{Error: "selector_not_found", SelectorError: missed}, missed.Error())
+		res.IsError = true
+		return res
+	}
+	return mcp.NewToolResultError(err.Error())
+}
@@ -1,7 +1,11 @@
 package tools
 
 import (
+	"cmp"
 	"context"
+	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -9,6 +13,14 @@ import (
 	web "github.com/leonardcser/web-mcp/internal/web"
 )
 
+const (
+	// defaultMaxLength is the default chunk size returned by web-fetch.
+	defaultMaxLength = 20000
+	// charsPerToken is the rough characters-per-token ratio used when the
+	// caller measures offsets in tokens.
+	charsPerToken = 4
+)
+
 // WebFetchHandler returns the MCP tool handler for the "web-fetch" tool.
 func WebFetchHandler(fetcher *web.Fetcher) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -19,20 +31,92 @@ func WebFetchHandler(fetcher *web.Fetcher) func(context.Context, mcp.CallToolReq
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
+		start := req.GetInt("start_index", 0)
+		if start < 0 {
+			return mcp.NewToolResultError("start_index must be >= 0"), nil
+		}
+		maxLen := req.GetInt("max_length", defaultMaxLength)
+		if maxLen <= 0 {
+			maxLen = defaultMaxLength
+		}
+		unit := req.GetString("unit", "chars")
+		if unit != "chars" && unit != "tokens" {
+			return mcp.NewToolResultError(`unit must be "chars" or "tokens"`), nil
+		}
 
-		ps, err := fetcher.Fetch(ctx, url)
+		mode, err := web.ParseMode(req.GetString("mode", string(web.ModeFull)))
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
+		links, err := web.ParseLinkFilter(req.GetString("links", string(web.LinksAll)))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		style, err := web.ParseLinkStyle(req.GetString("link_style", string(web.LinkStylePlain)))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		outline := req.GetBool("outline", false)
+		section := strings.TrimSpace(req.GetString("section", ""))
+		if outline && section != "" {
+			return mcp.NewToolResultError("outline and section cannot be used together"), nil
+		}
+
+		// Later chunks of the same URL are served from the cached PageSummary.
+		ps, err := fetcher.Fetch(ctx, url, web.FetchOptions{
+			Mode:      mode,
+			LinkStyle: style,
+			Selector:  req.GetString("selector", ""),
+		})
+		if err != nil {
+			return errorResult(err), nil
+		}
 
 		// Format the parsed content as a readable string
-		content := formatPageSummary(ps)
-		return mcp.NewToolResultText(content), nil
+		var content string
+		switch {
+		case ps.RedirectURL != "":
+			content = formatPageSummary(ps, links)
+		case outline:
+			content = formatOutline(ps)
+		case section != "":
+			_, text, err := ps.Section(section)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			content = text
+		default:
+			content = formatPageSummary(ps, links)
+		}
+		args := []string{fmt.Sprintf("url=%q", url), fmt.Sprintf("mode=%q", mode)}
+		for _, a := range []struct{ name, value, def string }{
+			{"unit", unit, "chars"},
+			{"selector", req.GetString("selector", ""), ""},
+			{"section", section, ""},
+			{"links", string(links), string(web.LinksAll)},
+			{"link_style", string(style), string(web.LinkStylePlain)},
+		} {
+			if a.value != a.def {
+				args = append(args, fmt.Sprintf("%s=%q", a.name, a.value))
+			}
+		}
+		chunk, err := paginate(content, start, maxLen, unit, args)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(chunk), nil
 	}
 }
 
-func formatPageSummary(ps *web.PageSummary) string {
+func formatPageSummary(ps *web.PageSummary, filter web.LinkFilter) string {
 	var sb strings.Builder
+	if ps.RedirectURL != "" {
+		writeRedirect(&sb, ps)
+		return sb.String()
+	}
+	if len(ps.Redirects) > 0 {
+		sb.WriteString(fmt.Sprintf("Redirected from %s to %s\n\n", ps.Redirects[0], ps.URL))
+	}
 	if ps.Title != "" {
 		sb.WriteString("# ")
 		sb.WriteString(ps.Title)
@@ -42,15 +126,164 @@ func formatPageSummary(ps *web.PageSummary) string {
 		sb.WriteString(ps.Description)
 		sb.WriteString("\n\n")
 	}
-	if len(ps.Links) > 0 {
-		sb.WriteString("## Links\n")
-		for _, l := range ps.Links {
-			sb.WriteString("- ")
-			sb.WriteString(l)
-			sb.WriteString("\n")
+	writeMetadata(&sb, ps)
+	writeLinks(&sb, filter.Apply(ps.Links))
+	sb.WriteString(ps.Text)
+	return sb.String()
+}
+
+// formatOutline lists the headings of ps as a nested list. Each entry shows
+// the anchor to pass as section and the length of that section.
+func formatOutline(ps *web.PageSummary) string {
+	var sb strings.Builder
+	if ps.Title != "" {
+		sb.WriteString("# ")
+		sb.WriteString(ps.Title)
+		sb.WriteString("\n\n")
+	}
+	if len(ps.Outline) == 0 {
+		sb.WriteString("The page has no headings.\n")
+		return sb.String()
+	}
+	sb.WriteString(fmt.Sprintf("Outline (%d headings). Call web-fetch with section set to an anchor to read that section.\n\n", len(ps.Outline)))
+	top := slices.MinFunc(ps.Outline, func(a, b web.Heading) int { return cmp.Compare(a.Level, b.Level) }).Level
+	total := len([]rune(ps.Text))
+	for i, h := range ps.Outline {
+		end := total
+		for _, next := range ps.Outline[i+1:] {
+			if next.Level <= h.Level {
+				end = next.Offset
+				break
+			}
 		}
-		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf("%s- %s (#%s, %d chars)\n", strings.Repeat("  ", h.Level-top), h.Title, h.Anchor, end-h.Offset))
 	}
-	sb.WriteString(ps.Text)
 	return sb.String()
 }
+
+// writeMetadata writes one "Key: value" line per known metadata field,
+// followed by a line per structured data item.
+func writeMetadata(sb *strings.Builder, ps *web.PageSummary) {
+	fields := []struct{ name, value string }{
+		{"Author", ps.Author},
+		{"Published", ps.Published},
+		{"Modified", ps.Modified},
+		{"Site", ps.SiteName},
+		{"Type", ps.Type},
+		{"Language", ps.Language},
+		{"Canonical", ps.Canonical},
+		{"Image", ps.Image},
+	}
+	if ps.PageCount > 0 {
+		fields = append(fields, struct{ name, value string }{"Pages", fmt.Sprint(ps.PageCount)})
+	}
+	n := 0
+	for _, f := range fields {
+		if f.value != "" {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", f.name, f.value))
+			n++
+		}
+	}
+	if len(ps.StructuredData) > 0 {
+		sb.WriteString("Structured data:\n")
+		for _, it := range ps.StructuredData {
+			keys := make([]string, 0, len(it.Properties))
+			for k := range it.Properties {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			pairs := make([]string, len(keys))
+			for i, k := range keys {
+				pairs[i] = k + "=" + it.Properties[k]
+			}
+			sb.WriteString(fmt.Sprintf("- %s (%s): %s\n", cmp.Or(it.Type, "Thing"), it.Source, strings.Join(pairs, "; ")))
+		}
+		n++
+	}
+	if n > 0 {
+		sb.WriteString("\n")
+	}
+}
+
+// writeLinks writes one line per link in document order, annotated with
+// its scope, rel and the section it appears under. Links already rendered
+// in the text are skipped.
+func writeLinks(sb *strings.Builder, links []web.Link) {
+	links = slices.DeleteFunc(slices.Clone(links), func(l web.Link) bool { return l.Inline })
+	if len(links) == 0 {
+		return
+	}
+	sb.WriteString("## Links\n")
+	for _, l := range links {
+		notes := []string{string(l.Scope)}
+		if l.Rel != "" {
+			notes = append(notes, "rel="+l.Rel)
+		}
+		if l.Section != "" {
+			notes = append(notes, fmt.Sprintf("under %q", l.Section))
+		}
+		if l.Text != "" {
+			sb.WriteString(fmt.Sprintf("- [%s](%s)", l.Text, l.URL))
+		} else {
+			sb.WriteString("- " + l.URL)
+		}
+		sb.WriteString(" (" + strings.Join(notes, ", ") + ")\n")
+	}
+	sb.WriteString("\n")
+}
+
+// writeRedirect describes a cross-host redirect that was not followed.
+// The block starts with a fixed "REDIRECT DETECTED" line and ends with the
+// exact url argument to use, so callers can follow it without guessing.
+func writeRedirect(sb *strings.Builder, ps *web.PageSummary) {
+	sb.WriteString("REDIRECT DETECTED: the URL redirects to a different host.\n\n")
+	sb.WriteString("Redirect chain:\n")
+	chain := append(append([]string(nil), ps.Redirects...), ps.URL, ps.RedirectURL)
+	for i, u := range chain {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, u))
+	}
+	sb.WriteString(fmt.Sprintf("\nRedirect URL: %s\n", ps.RedirectURL))
+	sb.WriteString(fmt.Sprintf("\nTo fetch the redirected content, call web-fetch again with url=%q.\n", ps.RedirectURL))
+}
+
+// paginate returns the window of content starting at start and spanning at
+// most maxLen units. Units are characters (runes) or estimated tokens. When
+// the window does not cover the whole content, a footer with the total
+// length and the next start index is appended. args are the arguments,
+// formatted as name=value, to repeat alongside start_index so the next call
+// pages through the same content.
+func paginate(content string, start, maxLen int, unit string, args []string) (string, error) {
+	runes := []rune(content)
+	scale := 1
+	if unit == "tokens" {
+		scale = charsPerToken
+	}
+	total := (len(runes) + scale - 1) / scale
+	if start == 0 && maxLen >= total {
+		return content, nil
+	}
+	if start >= total {
+		return "", fmt.Errorf("start_index %d is beyond the end of the content (total length %d %s)", start, total, unit)
+	}
+	end := min(start+maxLen, total)
+	chunk := string(runes[start*scale : min(end*scale, len(runes))])
+
+	var sb strings.Builder
+	sb.WriteString(chunk)
+	sb.WriteString("\n\n---\n")
+	if end < total {
+		args = append(slices.Clone(args), fmt.Sprintf("start_index=%d", end))
+		sb.WriteString(fmt.Sprintf("Showing %s %d-%d of %d total. next_start_index: %d (call web-fetch again with %s to continue)", unit, start, end, total, end, joinArgs(args)))
+	} else {
+		sb.WriteString(fmt.Sprintf("Showing %s %d-%d of %d total. End of content.", unit, start, end, total))
+	}
+	return sb.String(), nil
+}
+
+// joinArgs lists args as "a, b and c".
+func joinArgs(args []string) string {
+	if len(args) < 2 {
+		return strings.Join(args, "")
+	}
+	return strings.Join(args[:len(args)-1], ", ") + " and " + args[len(args)-1]
+}
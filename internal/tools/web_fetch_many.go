@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/leonardcser/web-mcp/internal/urls"
+	web "github.com/leonardcser/web-mcp/internal/web"
+)
+
+const (
+	// maxBatchURLs is the largest number of URLs web-fetch-many accepts.
+	maxBatchURLs = 20
+	// defaultBatchLength is the default character budget shared by all
+	// pages of a web-fetch-many call.
+	defaultBatchLength = 50000
+	defaultConcurrency = 4
+	maxConcurrency     = 8
+)
+
+// WebFetchManyHandler returns the MCP tool handler for the
+// "web-fetch-many" tool.
+func WebFetchManyHandler(fetcher *web.Fetcher) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if ctx.Err() != nil {
+			return mcp.NewToolResultError(ctx.Err().Error()), nil
+		}
+		list, err := req.RequireStringSlice("urls")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		list = urls.Unique(list)
+		if len(list) == 0 {
+			return mcp.NewToolResultError("urls must contain at least one URL"), nil
+		}
+		if len(list) > maxBatchURLs {
+			return mcp.NewToolResultError(fmt.Sprintf("at most %d urls can be fetched at once", maxBatchURLs)), nil
+		}
+		budget := req.GetInt("max_total_length", defaultBatchLength)
+		if budget <= 0 {
+			budget = defaultBatchLength
+		}
+		concurrency := req.GetInt("concurrency", defaultConcurrency)
+		if concurrency <= 0 || concurrency > maxConcurrency {
+			concurrency = defaultConcurrency
+		}
+		mode, err := web.ParseMode(req.GetString("mode", string(web.ModeFull)))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		results := fetcher.FetchMany(ctx, list, web.FetchOptions{Mode: mode}, concurrency)
+		return mcp.NewToolResultText(formatBatch(results, budget, mode)), nil
+	}
+}
+
+// formatBatch renders one section per result. Successful pages share the
+// character budget: pages shorter than an even share are shown in full and
+// the rest of their share goes to longer pages. Truncated pages end with
+// the web-fetch call that continues them, with the same mode so the
+// offsets match.
+func formatBatch(results []web.FetchResult, budget int, mode web.Mode) string {
+	contents := make([][]rune, len(results))
+	failed := 0
+	for i, r := range results {
+		if r.Err != nil {
+			failed++
+			continue
+		}
+		contents[i] = []rune(formatPageSummary(r.Summary, web.LinksAll))
+	}
+	limits := shareBudget(contents, results, budget)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Fetched %d URLs: %d succeeded, %d failed.\n", len(results), len(results)-failed, failed))
+	for i, r := range results {
+		sb.WriteString(fmt.Sprintf("\n===== [%d/%d] %s =====\n\n", i+1, len(results), r.URL))
+		if r.Err != nil {
+			sb.WriteString("ERROR: ")
+			sb.WriteString(r.Err.Error())
+			sb.WriteString("\n")
+			continue
+		}
+		content := contents[i]
+		if limit := limits[i]; len(content) > limit {
+			sb.WriteString(string(content[:limit]))
+			sb.WriteString(fmt.Sprintf("\n\n[Truncated: showing %d of %d chars. Call web-fetch with url=%q, mode=%q and start_index=%d to continue.]\n",
+				limit, len(content), r.URL, mode, limit))
+			continue
+		}
+		sb.WriteString(string(content))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// shareBudget splits budget among the successful results, shortest first,
+// so that no page gets less than an even share unless it needs less.
+func shareBudget(contents [][]rune, results []web.FetchResult, budget int) []int {
+	var order []int
+	for i, r := range results {
+		if r.Err == nil {
+			order = append(order, i)
+		}
+	}
+	slices.SortFunc(order, func(a, b int) int { return len(contents[a]) - len(contents[b]) })
+	limits := make([]int, len(results))
+	for n, i := range order {
+		share := budget / (len(order) - n)
+		limits[i] = min(len(contents[i]), share)
+		budget -= limits[i]
+	}
+	return limits
+}
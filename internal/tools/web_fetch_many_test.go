@@ -0,0 +1,20 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	web "github.com/leonardcser/web-mcp/internal/web"
+)
+
+func TestFormatBatchTruncationHintKeepsMode(t *testing.T) {
+	results := []web.FetchResult{{
+		URL:     "https://example.com/long",
+		Summary: &web.PageSummary{URL: "https://example.com/long", Text: strings.Repeat("x", 500)},
+	}}
+	out := formatBatch(results, 100, web.ModeFull)
+	want := `Call web-fetch with url="https://example.com/long", mode="full" and start_index=100 to continue.`
+	if !strings.Contains(out, want) {
+		t.Fatalf("output lacks %q:\n%s", want, out)
+	}
+}
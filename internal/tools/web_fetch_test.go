@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/leonardcser/web-mcp/internal/cache"
+	web "github.com/leonardcser/web-mcp/internal/web"
+)
+
+func TestWebFetchHintRepeatsArguments(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body><h1>Intro</h1><p>Hi</p><h2 id=\"install\">Install</h2><p>%s</p></body></html>", strings.Repeat("step ", 200))
+	}))
+	defer srv.Close()
+	fetcher, err := web.NewFetcher(cache.NewMemory(0, time.Minute), web.FetcherOptions{Allow: []string{"127.0.0.1", "::1"}, HostDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var req mcp.CallToolRequest
+	req.Params.Arguments = map[string]any{
+		"url":        srv.URL,
+		"mode":       "full",
+		"section":    "#install",
+		"link_style": "inline",
+		"unit":       "tokens",
+		"max_length": 10,
+	}
+	res, err := WebFetchHandler(fetcher)(context.Background(), req)
+	if err != nil || res.IsError {
+		t.Fatalf("web-fetch failed: %v %+v", err, res.Content)
+	}
+	out := res.Content[0].(mcp.TextContent).Text
+	want := fmt.Sprintf(`call web-fetch again with url=%q, mode="full", unit="tokens", section="#install", link_style="inline" and start_index=10 to continue`, srv.URL)
+	if !strings.Contains(out, want) {
+		t.Errorf("output lacks %q:\n%s", want, out)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	args := []string{`url="https://example.com/"`, `mode="full"`}
+	for _, tc := range []struct {
+		name          string
+		content       string
+		start, maxLen int
+		unit          string
+		want          string
+	}{
+		{
+			name:    "fits",
+			content: "0123456789", maxLen: 10, unit: "chars",
+			want: "0123456789",
+		},
+		{
+			name:    "first chunk",
+			content: "0123456789", maxLen: 4, unit: "chars",
+			want: "0123\n\n---\nShowing chars 0-4 of 10 total. next_start_index: 4 (call web-fetch again with url=\"https://example.com/\", mode=\"full\" and start_index=4 to continue)",
+		},
+		{
+			name:    "last chunk",
+			content: "0123456789", start: 8, maxLen: 4, unit: "chars",
+			want: "89\n\n---\nShowing chars 8-10 of 10 total. End of content.",
+		},
+		{
+			name:    "ends on the boundary",
+			content: "0123456789", start: 6, maxLen: 4, unit: "chars",
+			want: "6789\n\n---\nShowing chars 6-10 of 10 total. End of content.",
+		},
+		{
+			name:    "runes",
+			content: "héllo wörld", start: 1, maxLen: 4, unit: "chars",
+			want: "éllo\n\n---\nShowing chars 1-5 of 11 total. next_start_index: 5 (call web-fetch again with url=\"https://example.com/\", mode=\"full\" and start_index=5 to continue)",
+		},
+		{
+			name:    "tokens",
+			content: "0123456789", maxLen: 2, unit: "tokens",
+			want: "01234567\n\n---\nShowing tokens 0-2 of 3 total. next_start_index: 2 (call web-fetch again with url=\"https://example.com/\", mode=\"full\" and start_index=2 to continue)",
+		},
+		{
+			name:    "partial last token",
+			content: "0123456789", start: 2, maxLen: 2, unit: "tokens",
+			want: "89\n\n---\nShowing tokens 2-3 of 3 total. End of content.",
+		},
+		{
+			name:    "tokens fit",
+			content: "0123456789", maxLen: 3, unit: "tokens",
+			want: "0123456789",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := paginate(tc.content, tc.start, tc.maxLen, tc.unit, args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPaginateBeyondEnd(t *testing.T) {
+	for _, tc := range []struct {
+		start int
+		unit  string
+	}{
+		{start: 10, unit: "chars"},
+		{start: 11, unit: "chars"},
+		{start: 3, unit: "tokens"},
+	} {
+		_, err := paginate("0123456789", tc.start, 4, tc.unit, nil)
+		if err == nil || !strings.Contains(err.Error(), "beyond the end") {
+			t.Errorf("start %d %s: err = %v, want beyond the end", tc.start, tc.unit, err)
+		}
+	}
+}
+
+func TestJoinArgs(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"a=1"}, "a=1"},
+		{[]string{"a=1", "b=2"}, "a=1 and b=2"},
+		{[]string{"a=1", "b=2", "c=3"}, "a=1, b=2 and c=3"},
+	} {
+		if got := joinArgs(tc.args); got != tc.want {
+			t.Errorf("joinArgs(%q) = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestWriteMetadata(t *testing.T) {
+	var sb strings.Builder
+	writeMetadata(&sb, &web.PageSummary{
+		Author:    "Jane Doe",
+		Canonical: "https://example.com/a",
+		Image:     "https://example.com/cover.png",
+		PageCount: 3,
+	})
+	want := "Author: Jane Doe\nCanonical: https://example.com/a\nImage: https://example.com/cover.png\nPages: 3\n\n"
+	if got := sb.String(); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
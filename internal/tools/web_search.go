@@ -17,16 +17,38 @@ func WebSearchHandler(searcher *web.Searcher) func(context.Context, mcp.CallTool
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		results, err := searcher.Search(ctx, q, 10)
+		page := req.GetInt("page", 1)
+		if page == 0 {
+			page = 1
+		}
+		limit := req.GetInt("max_results", 10)
+		if limit <= 0 || limit > web.MaxSearchResults {
+			limit = 10
+		}
+		results, err := searcher.Search(ctx, web.SearchRequest{
+			Query:      q,
+			Limit:      limit,
+			Region:     req.GetString("region", ""),
+			Language:   req.GetString("language", ""),
+			TimeRange:  web.TimeRange(req.GetString("time_range", "")),
+			SafeSearch: web.SafeSearch(req.GetString("safe_search", "")),
+			Page:       page,
+		})
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		return mcp.NewToolResultText(formatSearchResults(results)), nil
+		out := formatSearchResults(results, (page-1)*limit+1)
+		// A short page means the results ran out.
+		if len(results) == limit && page < web.MaxSearchPage {
+			out += fmt.Sprintf("\n\n(Page %d. Call web-search again with page=%d for more results.)", page, page+1)
+		}
+		return mcp.NewToolResultText(out), nil
 	}
 }
 
-// formatSearchResults renders an ordered list and ensures only a single URL line.
-func formatSearchResults(results []web.SearchResult) string {
+// formatSearchResults renders an ordered list numbered from first and ensures
+// only a single URL line.
+func formatSearchResults(results []web.SearchResult, first int) string {
 	if len(results) == 0 {
 		return "No results."
 	}
@@ -36,7 +58,7 @@ func formatSearchResults(results []web.SearchResult) string {
 		link := r.Link
 		desc := r.Description
 
-		sb.WriteString(fmt.Sprintf("%d. %s\n   %s", i+1, title, link))
+		sb.WriteString(fmt.Sprintf("%d. %s\n   %s", first+i, title, link))
 		if desc != "" {
 			sb.WriteString("\n   ")
 			sb.WriteString(desc)
@@ -0,0 +1,23 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	web "github.com/leonardcser/web-mcp/internal/web"
+)
+
+func TestFormatSearchResultsNumbersAcrossPages(t *testing.T) {
+	results := []web.SearchResult{
+		{Title: "Eleventh", Link: "https://a.example.com/", Description: "First on page 2"},
+		{Title: "Twelfth", Link: "https://b.example.com/"},
+	}
+	out := formatSearchResults(results, 11)
+	want := "11. Eleventh\n   https://a.example.com/\n   First on page 2\n\n12. Twelfth\n   https://b.example.com/"
+	if out != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+	if !strings.HasPrefix(formatSearchResults(results, 1), "1. Eleventh") {
+		t.Error("first page does not start at 1")
+	}
+}
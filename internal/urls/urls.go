@@ -0,0 +1,20 @@
+// Package urls holds helpers for the URL lists taken by the tools.
+package urls
+
+import "strings"
+
+// Unique drops blank and repeated URLs, keeping the first occurrence. URLs
+// are trimmed of surrounding whitespace.
+func Unique(urls []string) []string {
+	seen := make(map[string]bool, len(urls))
+	out := make([]string, 0, len(urls))
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		out = append(out, u)
+	}
+	return out
+}
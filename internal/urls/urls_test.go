@@ -0,0 +1,17 @@
+package urls
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnique(t *testing.T) {
+	got := Unique([]string{" https://a.example ", "", "https://b.example", "https://a.example", "  ", "https://B.example"})
+	want := []string{"https://a.example", "https://b.example", "https://B.example"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unique = %q, want %q", got, want)
+	}
+	if got := Unique(nil); len(got) != 0 {
+		t.Errorf("Unique(nil) = %q", got)
+	}
+}
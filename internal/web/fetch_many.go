@@ -0,0 +1,57 @@
+package web
+
+import (
+	"context"
+	"net/url"
+	"sync"
+)
+
+// FetchResult is the outcome of one URL in a FetchMany batch.
+type FetchResult struct {
+	URL     string
+	Summary *PageSummary
+	Err     error
+}
+
+// FetchMany fetches urls concurrently and returns one result per URL, in
+// the order given. At most concurrency fetches run at once, and at most
+// HostParallelism of them target the same host; URLs waiting for a busy
+// host do not hold a global slot.
+func (f *Fetcher) FetchMany(ctx context.Context, urls []string, opts FetchOptions, concurrency int) []FetchResult {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	results := make([]FetchResult, len(urls))
+	global := make(chan struct{}, concurrency)
+	hosts := make(map[string]chan struct{})
+
+	var wg sync.WaitGroup
+	for i, rawURL := range urls {
+		results[i].URL = rawURL
+		host := rawURL
+		if u, err := url.Parse(rawURL); err == nil {
+			host = u.Host
+		}
+		sem, ok := hosts[host]
+		if !ok {
+			sem = make(chan struct{}, f.opts.HostParallelism)
+			hosts[host] = sem
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, s := range []chan struct{}{sem, global} {
+				select {
+				case s <- struct{}{}:
+					defer func() { <-s }()
+				case <-ctx.Done():
+					results[i].Err = ctx.Err()
+					return
+				}
+			}
+			results[i].Summary, results[i].Err = f.Fetch(ctx, rawURL, opts)
+		}()
+	}
+	wg.Wait()
+	return results
+}
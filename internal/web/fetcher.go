@@ -1,62 +1,225 @@
 package web
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"net/url"
-	"sort"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/JohannesKaufmann/html-to-markdown/v2"
 	"github.com/PuerkitoBio/goquery"
-	"github.com/gocolly/colly/v2"
+	"golang.org/x/net/html/charset"
+
 	"github.com/leonardcser/web-mcp/internal/cache"
 )
 
 const (
 	RequestTimeout  = 20 * time.Second
 	MaxResponseSize = 1 * 1024 * 1024 // 1MB
+	// HostDelay is the minimum delay between two requests to the same host.
+	HostDelay = 1 * time.Second
 )
 
+// Mode selects how much of an HTML page is converted to text.
+type Mode string
+
+const (
+	// ModeArticle keeps only the main content picked by the readability
+	// scorer, falling back to the full page when no article is found.
+	ModeArticle Mode = "article"
+	// ModeFull converts the whole page minus scripts, headers and footers.
+	ModeFull Mode = "full"
+)
+
+// ParseMode validates s as a Mode. An empty string selects ModeFull, which
+// is how pages were converted before article extraction existed.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeFull:
+		return ModeFull, nil
+	case ModeArticle:
+		return ModeArticle, nil
+	}
+	return "", fmt.Errorf("invalid mode %q: must be %q or %q", s, ModeArticle, ModeFull)
+}
+
+// FetchOptions controls how a page is parsed. Every field changes the
+// resulting PageSummary and is therefore part of the cache key.
+type FetchOptions struct {
+	Mode      Mode
+	LinkStyle LinkStyle
+	// Selector is a CSS selector, or an XPath expression starting with
+	// "/", "./" or "(", that limits an HTML page to the matched elements.
+	Selector string
+}
+
 type PageSummary struct {
-	URL         string   `json:"url"`
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Text        string   `json:"text"`
-	Links       []string `json:"links"`
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Text        string `json:"text"`
+	Links       []Link `json:"links"`
+	// Outline lists the markdown headings of Text in order.
+	Outline []Heading `json:"outline,omitempty"`
+	Author  string    `json:"author,omitempty"`
+	// PageCount is only set for PDF documents.
+	PageCount int `json:"page_count,omitempty"`
+	// The remaining metadata comes from HTML meta tags, OpenGraph and
+	// Twitter cards, JSON-LD and microdata. Dates are kept as published,
+	// usually in ISO 8601.
+	Canonical      string           `json:"canonical,omitempty"`
+	Language       string           `json:"language,omitempty"`
+	SiteName       string           `json:"site_name,omitempty"`
+	Type           string           `json:"type,omitempty"`
+	Image          string           `json:"image,omitempty"`
+	Published      string           `json:"published,omitempty"`
+	Modified       string           `json:"modified,omitempty"`
+	StructuredData []StructuredItem `json:"structured_data,omitempty"`
+	// Redirects lists the URLs visited before URL, starting with the
+	// requested one. It is empty when the request was not redirected.
+	Redirects []string `json:"redirects,omitempty"`
+	// RedirectURL is set when a redirect to a different host was not
+	// followed. The summary then has no content; fetch RedirectURL instead.
+	RedirectURL string `json:"redirect_url,omitempty"`
 }
 
+// FetcherOptions configures a Fetcher.
+type FetcherOptions struct {
+	// DefaultTTL is how long a page stays fresh when its response carries
+	// no caching headers.
+	DefaultTTL time.Duration
+	// MinTTL and MaxTTL bound the freshness computed from Cache-Control,
+	// Expires and Last-Modified. Zero disables the respective bound.
+	MinTTL time.Duration
+	MaxTTL time.Duration
+	// Allow lists CIDR ranges, IP addresses and hostnames that may be
+	// fetched even though they are private, loopback or link-local. All
+	// other internal destinations are refused.
+	Allow []string
+	// Policy restricts which hosts may be fetched, including redirect
+	// targets. Nil allows every host.
+	Policy *Policy
+	// Timeout, MaxResponseSize, MaxPDFSize and HostDelay default to the
+	// package constants of the same name when zero.
+	Timeout         time.Duration
+	MaxResponseSize int
+	MaxPDFSize      int
+	HostDelay       time.Duration
+	// HostParallelism is the number of concurrent requests allowed per
+	// host. It defaults to 1.
+	HostParallelism int
+}
+
+// Fetcher downloads and parses web pages. It is safe for concurrent use by
+// multiple goroutines: all per-request state lives in the call to Fetch, and
+// the shared http.Client and host throttle are concurrency-safe.
 type Fetcher struct {
-	c     *colly.Collector
-	cache cache.KV
-	ttl   time.Duration
-}
-
-func NewFetcher(cacheStore cache.KV, ttl time.Duration) *Fetcher {
-	c := colly.NewCollector(
-		colly.AllowURLRevisit(),
-		colly.Async(false),
-	)
-	c.Limit(&colly.LimitRule{
-		DomainGlob:  "*",
-		Parallelism: 1,
-		Delay:       1 * time.Second,
-	})
-	c.SetRequestTimeout(RequestTimeout)
-	c.OnRequest(func(r *colly.Request) {
-		r.Headers.Set("User-Agent", NextUserAgent())
-		r.Headers.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-		r.Headers.Set("Accept-Language", "en-US,en;q=0.9")
-	})
-	return &Fetcher{c: c, cache: cacheStore, ttl: ttl}
-}
-
-func (f *Fetcher) cacheKey(rawURL string) string { return "web_fetch|" + rawURL }
-
-func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*PageSummary, error) {
+	client   *http.Client
+	cache    cache.KV
+	opts     FetcherOptions
+	throttle *hostThrottle
+	guard    *addressGuard
+}
+
+func NewFetcher(cacheStore cache.KV, opts FetcherOptions) (*Fetcher, error) {
+	guard, err := newAddressGuard(opts.Allow)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = RequestTimeout
+	}
+	if opts.MaxResponseSize <= 0 {
+		opts.MaxResponseSize = MaxResponseSize
+	}
+	if opts.MaxPDFSize <= 0 {
+		opts.MaxPDFSize = MaxPDFSize
+	}
+	if opts.HostDelay <= 0 {
+		opts.HostDelay = HostDelay
+	}
+	if opts.HostParallelism <= 0 {
+		opts.HostParallelism = 1
+	}
+	f := &Fetcher{
+		cache:    cacheStore,
+		opts:     opts,
+		throttle: newHostThrottle(opts.HostParallelism, opts.HostDelay),
+		guard:    guard,
+	}
+	f.client = &http.Client{
+		Timeout:       opts.Timeout,
+		Transport:     guard.transport(),
+		CheckRedirect: f.checkRedirect,
+	}
+	return f, nil
+}
+
+// checkRedirect vets every redirect hop before it is followed. Redirects to
+// a different host are not followed; the redirect response is returned so
+// the caller can decide whether to fetch the new location.
+func (f *Fetcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !sameHost(via[0].URL, req.URL) {
+		return http.ErrUseLastResponse
+	}
+	if err := f.opts.Policy.Check(req.URL); err != nil {
+		return err
+	}
+	return f.guard.checkHost(req.URL.Hostname())
+}
+
+// response holds the raw result of a single download.
+type response struct {
+	finalURL    string
+	contentType string
+	body        []byte
+	header      http.Header
+	// notModified is set when a conditional request was answered with 304.
+	notModified bool
+	// redirects lists the URLs visited before finalURL.
+	redirects []string
+	// redirectURL is the cross-host location that was not followed.
+	redirectURL string
+	// maxText bounds the size of text extracted from the body.
+	maxText int
+}
+
+// sameHost reports whether a and b name the same host, ignoring case, the
+// port and a leading "www.".
+func sameHost(a, b *url.URL) bool {
+	trim := func(u *url.URL) string {
+		return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	}
+	return trim(a) == trim(b)
+}
+
+// redirectChain returns the URLs that were redirected to reach req, oldest
+// first.
+func redirectChain(req *http.Request) []string {
+	var chain []string
+	for r := req.Response; r != nil && r.Request != nil; r = r.Request.Response {
+		chain = append(chain, r.Request.URL.String())
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+func (f *Fetcher) cacheKey(rawURL string, opts FetchOptions) string {
+	return strings.Join([]string{"web_fetch", string(opts.Mode), string(opts.LinkStyle), opts.Selector, rawURL}, "|")
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string, opts FetchOptions) (*PageSummary, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -66,125 +229,295 @@ func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*PageSummary, error
 	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
 		return nil, errors.New("url must start with http:// or https://")
 	}
-	if v, err := f.cache.Get(f.cacheKey(rawURL)); err == nil {
-		var ps PageSummary
-		if json.Unmarshal(v, &ps) == nil {
-			return &ps, nil
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	// Checked before the cache so policy changes apply to cached pages too.
+	if err := f.opts.Policy.Check(u); err != nil {
+		return nil, err
+	}
+	mode, err := ParseMode(string(opts.Mode))
+	if err != nil {
+		return nil, err
+	}
+	opts.Mode = mode
+	style, err := ParseLinkStyle(string(opts.LinkStyle))
+	if err != nil {
+		return nil, err
+	}
+	opts.LinkStyle = style
+	opts.Selector = strings.TrimSpace(opts.Selector)
+	key := f.cacheKey(rawURL, opts)
+	var stale *cachedPage
+	if v, err := f.cache.Get(key); err == nil {
+		var entry cachedPage
+		if json.Unmarshal(v, &entry) == nil && entry.Summary != nil {
+			if entry.fresh(time.Now()) {
+				return entry.Summary, nil
+			}
+			if entry.hasValidators() {
+				stale = &entry
+			}
 		}
 	}
-	var pageHTML []byte
-	var finalURL string
-	var fetchErr error
 
-	var contentType string
+	resp, err := f.download(ctx, rawURL, stale)
+	if err != nil {
+		return nil, err
+	}
+	if resp.notModified {
+		// The stale copy is still valid; only its freshness is renewed.
+		f.store(key, stale.Summary, resp.header, stale)
+		return stale.Summary, nil
+	}
+	ps, err := summarize(resp, opts)
+	if err != nil {
+		return nil, err
+	}
+	f.store(key, ps, resp.header, nil)
+	return ps, nil
+}
+
+// store caches ps with a freshness lifetime derived from the response
+// headers. Entries with validators are kept past their freshness so they can
+// be revalidated cheaply. prev supplies validators a 304 response omitted.
+// Responses marked no-store are not cached, and those that must be
+// revalidated are not raised to MinTTL.
+func (f *Fetcher) store(key string, ps *PageSummary, h http.Header, prev *cachedPage) {
+	noStore, revalidate := reuseRestrictions(h)
+	if noStore {
+		_ = f.cache.Delete(key)
+		return
+	}
+	now := time.Now()
+	ttl, ok := freshness(h, now)
+	if !ok {
+		ttl = f.opts.DefaultTTL
+	}
+	if f.opts.MinTTL > 0 && !revalidate {
+		ttl = max(ttl, f.opts.MinTTL)
+	}
+	if f.opts.MaxTTL > 0 {
+		ttl = min(ttl, f.opts.MaxTTL)
+	}
+	entry := cachedPage{
+		Summary:      ps,
+		ETag:         h.Get("ETag"),
+		LastModified: h.Get("Last-Modified"),
+		FreshUntil:   now.Add(ttl),
+	}
+	if prev != nil {
+		if entry.ETag == "" {
+			entry.ETag = prev.ETag
+		}
+		if entry.LastModified == "" {
+			entry.LastModified = prev.LastModified
+		}
+	}
+	keep := ttl
+	if entry.hasValidators() {
+		keep += max(f.opts.MaxTTL, f.opts.DefaultTTL)
+	}
+	if keep <= 0 {
+		return
+	}
+	if b, err := json.Marshal(&entry); err == nil {
+		_ = f.cache.Put(key, b, keep)
+	}
+}
 
-	originalCtx := f.c.Context
-	f.c.Context = ctx
-	defer func() { f.c.Context = originalCtx }()
+// download performs the HTTP request for rawURL and reads at most
+// opts.MaxResponseSize bytes of the body, appending a marker when it was
+// trimmed. PDF documents are read in full up to opts.MaxPDFSize. When stale
+// is non-nil, its validators make the request conditional.
+func (f *Fetcher) download(ctx context.Context, rawURL string, stale *cachedPage) (*response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.guard.checkHost(u.Hostname()); err != nil {
+		return nil, err
+	}
+	release, err := f.throttle.acquire(ctx, u.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
-	f.c.OnResponse(func(r *colly.Response) {
-		if ctx.Err() != nil {
-			return
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", NextUserAgent())
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,application/pdf;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	if stale != nil {
+		if stale.ETag != "" {
+			req.Header.Set("If-None-Match", stale.ETag)
+		}
+		if stale.LastModified != "" {
+			req.Header.Set("If-Modified-Since", stale.LastModified)
 		}
-		finalURL = r.Request.URL.String()
-		pageHTML = append([]byte(nil), r.Body...)
-		contentType = r.Headers.Get("Content-Type")
-	})
+	}
 
-	fetchErr = f.c.Visit(rawURL)
-	if fetchErr != nil {
-		return nil, fetchErr
+	resp, err := f.client.Do(req)
+	if err != nil {
+		// Surface guard and policy rejections without the transport
+		// error wrapping.
+		var blocked *BlockedAddressError
+		if errors.As(err, &blocked) {
+			return nil, blocked
+		}
+		var denied *PolicyError
+		if errors.As(err, &denied) {
+			return nil, denied
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && stale != nil {
+		return &response{finalURL: resp.Request.URL.String(), header: resp.Header, notModified: true}, nil
+	}
+	if loc := resp.Header.Get("Location"); loc != "" && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		target, err := resp.Request.URL.Parse(loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirect location %q: %w", loc, err)
+		}
+		return &response{
+			finalURL:    resp.Request.URL.String(),
+			header:      resp.Header,
+			redirects:   redirectChain(resp.Request),
+			redirectURL: target.String(),
+		}, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
 	}
 
+	contentType := resp.Header.Get("Content-Type")
+	maxSize := f.opts.MaxResponseSize
+	limit := int64(maxSize)
+	br := bufio.NewReader(resp.Body)
+	head, _ := br.Peek(len(pdfMagic))
+	pdfExpected := expectPDF(contentType, resp.Request.URL, head)
+	if pdfExpected {
+		limit = int64(f.opts.MaxPDFSize)
+	}
+	body, err := io.ReadAll(io.LimitReader(br, limit+1))
+	if err != nil {
+		return nil, err
+	}
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-
-	if len(pageHTML) == 0 {
+	if len(body) == 0 {
 		return nil, errors.New("empty response body")
 	}
+	if pdfExpected && int64(len(body)) > limit {
+		return nil, fmt.Errorf("PDF document exceeds the maximum supported size of %d MB", f.opts.MaxPDFSize/(1024*1024))
+	}
+	if len(body) > maxSize && !pdfExpected {
+		body = body[:maxSize]
+		body = append(body, []byte("... [response trimmed due to size]")...)
+	}
+	return &response{
+		finalURL:    resp.Request.URL.String(),
+		contentType: contentType,
+		body:        body,
+		header:      resp.Header,
+		redirects:   redirectChain(resp.Request),
+		maxText:     maxSize,
+	}, nil
+}
 
-	if len(pageHTML) > MaxResponseSize {
-		pageHTML = pageHTML[:MaxResponseSize]
-		pageHTML = append(pageHTML, []byte("... [response trimmed due to size]")...)
+// summarize converts a downloaded response into a PageSummary.
+func summarize(resp *response, opts FetchOptions) (*PageSummary, error) {
+	ps, err := summarizeBody(resp, opts)
+	if err != nil {
+		return nil, err
+	}
+	ps.Redirects = resp.redirects
+	if ps.Outline == nil {
+		ps.Outline = buildOutline(ps.Text, nil)
+	}
+	return ps, nil
+}
+
+func summarizeBody(resp *response, opts FetchOptions) (*PageSummary, error) {
+	if resp.redirectURL != "" {
+		return &PageSummary{URL: resp.finalURL, RedirectURL: resp.redirectURL}, nil
+	}
+	if isPDF(resp.contentType, resp.body) {
+		if opts.Selector != "" {
+			return nil, errors.New("selector is only supported for HTML pages, not PDF documents")
+		}
+		return summarizePDF(resp)
 	}
 
-	lowerCT := strings.ToLower(contentType)
+	lowerCT := strings.ToLower(resp.contentType)
 	isHTML := strings.Contains(lowerCT, "text/html")
 	isText := strings.HasPrefix(lowerCT, "text/")
 
 	if !isText {
-		return nil, errors.New("unsupported content type: binary files like images or PDFs are not supported")
+		return nil, errors.New("unsupported content type: binary files like images are not supported")
+	}
+	if opts.Selector != "" && !isHTML {
+		return nil, fmt.Errorf("selector is only supported for HTML pages, not %s", resp.contentType)
 	}
 
-	var title, desc, bodyText string
-	var links []string
+	body := resp.body
+	if r, err := charset.NewReader(bytes.NewReader(body), resp.contentType); err == nil {
+		if decoded, err := io.ReadAll(r); err == nil {
+			body = decoded
+		}
+	}
+
+	ps := &PageSummary{URL: resp.finalURL}
+	var bodyText string
+	var links []Link
 
 	if isHTML {
-		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(pageHTML))
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 		if err != nil {
 			return nil, err
 		}
+		base, _ := url.Parse(resp.finalURL)
+		extractMetadata(doc, base, ps)
+
+		// Scope the page to the requested elements before any cleanup
+		if opts.Selector != "" {
+			if err := scopeToSelector(doc, opts.Selector); err != nil {
+				return nil, err
+			}
+		}
 
 		// Remove non-visible elements
 		doc.Find("script, style, noscript, iframe, object, embed, img, video, picture, svg, canvas, audio, source, track, map, area, form, label, input, button, select, textarea, progress, ins, applet").Remove()
 
-		title = strings.TrimSpace(doc.Find("head > title").First().Text())
-		desc = strings.TrimSpace(doc.Find("meta[name=description]").AttrOr("content", ""))
+		// Keep only the main content when the scorer finds an article body.
+		if opts.Mode == ModeArticle && opts.Selector == "" {
+			if article := extractArticle(doc); article != nil {
+				body := doc.Find("body").First()
+				body.Empty()
+				body.AppendSelection(article)
+			}
+		}
 
 		plainText := strings.TrimSpace(doc.Find("body").Text())
 		plainText = strings.Join(strings.Fields(plainText), " ")
 
-		// Extract absolute links with filtering and deduplication
-		base, _ := url.Parse(finalURL)
-		linkSet := make(map[string]struct{})
-		doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
-			href := strings.TrimSpace(s.AttrOr("href", ""))
-			if href == "" || strings.HasPrefix(href, "javascript:") {
-				return
-			}
-			u, err := url.Parse(href)
-			if err != nil {
-				return
-			}
-			if !u.IsAbs() && base != nil {
-				u = base.ResolveReference(u)
-			}
-			abs := u.String()
-			linkSet[abs] = struct{}{}
-		})
-
-		// Filter, dedupe canonical, exclude unwanted schemes, remove fragments, limit to 50
-		canonicalSet := make(map[string]struct{})
-		for abs := range linkSet {
-			u, err := url.Parse(abs)
-			if err != nil {
-				continue
-			}
-			// Exclude unwanted schemes
-			if u.Scheme == "javascript" || u.Scheme == "mailto" || u.Scheme == "tel" || u.Scheme == "" {
-				continue
-			}
-			// Remove fragment
-			u.Fragment = ""
-			canon := u.String()
-			canonicalSet[canon] = struct{}{}
-		}
+		links = extractLinks(doc, base)
 
-		links = make([]string, 0, len(canonicalSet))
-		for canon := range canonicalSet {
-			links = append(links, canon)
-			if len(links) >= 50 {
-				break
-			}
+		// Remove header and footer, unless they were selected explicitly
+		if opts.Selector == "" {
+			doc.Find("header, footer, aside").Remove()
 		}
-		sort.Strings(links)
 
-		// Remove &lt;a&gt; elements after extracting links
-		doc.Find("a").Remove()
-
-		// Remove header and footer
-		doc.Find("header, footer, aside").Remove()
+		// Keep the anchor text, and the URL when the style asks for it
+		inline := rewriteAnchors(doc, base, opts.LinkStyle)
+		markInline(links, inline)
+		headings := collectHeadings(doc)
 
 		// Convert to Markdown
 		htmlStr, err := doc.Html()
@@ -195,22 +528,17 @@ func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*PageSummary, error
 		markdown, err := htmltomarkdown.ConvertString(string(htmlStr))
 		if err != nil {
 			bodyText = plainText
+		} else if opts.LinkStyle == LinkStyleReference {
+			bodyText = referenceLinks(markdown, inline)
 		} else {
 			bodyText = markdown
 		}
+		ps.Outline = buildOutline(bodyText, headings)
 	} else {
-		bodyText = string(pageHTML)
+		bodyText = string(body)
 	}
 
-	ps := &PageSummary{
-		URL:         finalURL,
-		Title:       title,
-		Description: desc,
-		Text:        bodyText,
-		Links:       links,
-	}
-	if b, err := json.Marshal(ps); err == nil {
-		_ = f.cache.Put(f.cacheKey(rawURL), b, f.ttl)
-	}
+	ps.Text = bodyText
+	ps.Links = links
 	return ps, nil
 }
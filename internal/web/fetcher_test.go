@@ -0,0 +1,180 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leonardcser/web-mcp/internal/cache"
+)
+
+// newTestFetcher returns a Fetcher with an in-memory cache that may reach
+// loopback test servers.
+func newTestFetcher(t *testing.T, opts FetcherOptions) *Fetcher {
+	t.Helper()
+	opts.Allow = append(opts.Allow, "127.0.0.1", "::1")
+	if opts.HostDelay == 0 {
+		opts.HostDelay = time.Millisecond
+	}
+	f, err := NewFetcher(cache.NewMemory(0, time.Minute), opts)
+	if err != nil {
+		t.Fatalf("NewFetcher: %v", err)
+	}
+	return f
+}
+
+func TestFetchConcurrent(t *testing.T) {
+	const parallelism = 2
+	var inFlight, peak, hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Cache-Control", "max-age=600")
+		fmt.Fprintf(w, "<html><head><title>Page %s</title></head><body><p>Body of %s</p></body></html>", r.URL.Path, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	f := newTestFetcher(t, FetcherOptions{HostParallelism: parallelism})
+	const pages, callers = 5, 4
+	var wg sync.WaitGroup
+	errs := make(chan error, pages*callers)
+	for i := range pages * callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path := fmt.Sprintf("/p%d", i%pages)
+			ps, err := f.Fetch(context.Background(), srv.URL+path, FetchOptions{Mode: ModeFull})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if ps.Title != "Page "+path || !strings.Contains(ps.Text, "Body of "+path) {
+				errs <- fmt.Errorf("%s: got title %q, text %q", path, ps.Title, ps.Text)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if p := peak.Load(); p > parallelism {
+		t.Errorf("peak concurrent requests = %d, want at most %d", p, parallelism)
+	}
+
+	// Every page is now cached and fresh.
+	before := hits.Load()
+	for i := range pages {
+		if _, err := f.Fetch(context.Background(), fmt.Sprintf("%s/p%d", srv.URL, i), FetchOptions{Mode: ModeFull}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after := hits.Load(); after != before {
+		t.Errorf("cached fetches reached the server %d times", after-before)
+	}
+}
+
+func TestFetchRefusesLoopbackByDefault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the server")
+	}))
+	defer srv.Close()
+
+	f, err := NewFetcher(cache.NewMemory(0, time.Minute), FetcherOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Fetch(context.Background(), srv.URL, FetchOptions{}); err == nil {
+		t.Fatal("fetching a loopback address succeeded without an allowlist")
+	}
+}
+
+func TestFetchCancelledWhileThrottled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	f := newTestFetcher(t, FetcherOptions{HostParallelism: 1})
+	go f.Fetch(context.Background(), srv.URL+"/slow", FetchOptions{})
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := f.Fetch(ctx, srv.URL+"/queued", FetchOptions{}); err == nil {
+		t.Fatal("queued fetch succeeded after its context expired")
+	}
+}
+
+func TestFetchHonorsReuseRestrictions(t *testing.T) {
+	for _, tc := range []struct {
+		cacheControl string
+		etag         string
+		wantHits     int32
+		want304      bool
+	}{
+		{cacheControl: "no-store", wantHits: 2},
+		{cacheControl: "no-cache", wantHits: 2},
+		{cacheControl: "private, max-age=600", wantHits: 2},
+		{cacheControl: "no-cache", etag: `"v1"`, wantHits: 2, want304: true},
+		{cacheControl: "max-age=600", wantHits: 1},
+	} {
+		t.Run(tc.cacheControl+tc.etag, func(t *testing.T) {
+			var hits, notModified atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := hits.Add(1)
+				w.Header().Set("Cache-Control", tc.cacheControl)
+				if tc.etag != "" {
+					w.Header().Set("ETag", tc.etag)
+					if r.Header.Get("If-None-Match") == tc.etag {
+						notModified.Add(1)
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+				}
+				w.Header().Set("Content-Type", "text/plain")
+				fmt.Fprintf(w, "response %d", n)
+			}))
+			defer srv.Close()
+
+			f := newTestFetcher(t, FetcherOptions{MinTTL: time.Minute, DefaultTTL: time.Minute})
+			first, err := f.Fetch(context.Background(), srv.URL, FetchOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := f.Fetch(context.Background(), srv.URL, FetchOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hits.Load(); got != tc.wantHits {
+				t.Errorf("server hit %d times, want %d", got, tc.wantHits)
+			}
+			if tc.want304 {
+				if notModified.Load() != 1 || second.Text != first.Text {
+					t.Errorf("revalidation: %d 304s, texts %q and %q", notModified.Load(), first.Text, second.Text)
+				}
+			} else if tc.wantHits == 2 && second.Text == first.Text {
+				t.Errorf("second fetch returned the cached body %q", second.Text)
+			}
+		})
+	}
+}
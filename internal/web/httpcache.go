@@ -0,0 +1,90 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cachedPage is the cache representation of a fetched page. The validators
+// allow a stale entry to be revalidated with a conditional request instead
+// of being downloaded again.
+type cachedPage struct {
+	Summary      *PageSummary `json:"summary"`
+	ETag         string       `json:"etag,omitempty"`
+	LastModified string       `json:"last_modified,omitempty"`
+	FreshUntil   time.Time    `json:"fresh_until"`
+}
+
+func (c *cachedPage) fresh(now time.Time) bool { return now.Before(c.FreshUntil) }
+
+func (c *cachedPage) hasValidators() bool { return c.ETag != "" || c.LastModified != "" }
+
+// freshness computes how long a response stays fresh from its caching
+// headers, following RFC 9111: s-maxage and max-age win over Expires, and a
+// Last-Modified heuristic is used when neither is present. ok is false when
+// the headers carry no freshness information at all. Directives that forbid
+// reuse (no-store, no-cache, private) yield zero.
+func freshness(h http.Header, now time.Time) (ttl time.Duration, ok bool) {
+	directives := cacheControl(h.Get("Cache-Control"))
+	for _, d := range []string{"no-store", "no-cache", "private"} {
+		if _, found := directives[d]; found {
+			return 0, true
+		}
+	}
+
+	var age time.Duration
+	if secs, err := strconv.ParseInt(h.Get("Age"), 10, 64); err == nil && secs > 0 {
+		age = time.Duration(secs) * time.Second
+	}
+	for _, d := range []string{"s-maxage", "max-age"} {
+		if v, found := directives[d]; found {
+			if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return max(time.Duration(secs)*time.Second-age, 0), true
+			}
+		}
+	}
+
+	date := now
+	if d, err := http.ParseTime(h.Get("Date")); err == nil {
+		date = d
+	}
+	if v := h.Get("Expires"); v != "" {
+		exp, err := http.ParseTime(v)
+		if err != nil {
+			// Invalid dates such as "0" mean already expired.
+			return 0, true
+		}
+		return max(exp.Sub(date), 0), true
+	}
+	if lm, err := http.ParseTime(h.Get("Last-Modified")); err == nil && lm.Before(date) {
+		return date.Sub(lm) / 10, true
+	}
+	return 0, false
+}
+
+// reuseRestrictions reports whether a response must not be stored at all
+// (no-store), or may only be reused after revalidation (no-cache, and
+// private since the cache is shared by every client of the server).
+func reuseRestrictions(h http.Header) (noStore, revalidate bool) {
+	directives := cacheControl(h.Get("Cache-Control"))
+	_, noStore = directives["no-store"]
+	_, noCache := directives["no-cache"]
+	_, private := directives["private"]
+	return noStore, noCache || private
+}
+
+// cacheControl parses a Cache-Control header into lower-cased directives.
+func cacheControl(v string) map[string]string {
+	out := make(map[string]string)
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, val, _ := strings.Cut(part, "=")
+		out[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(val), `"`)
+	}
+	return out
+}
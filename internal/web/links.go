@@ -0,0 +1,268 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/publicsuffix"
+)
+
+// maxLinks is the number of links kept per page, in document order.
+const maxLinks = 50
+
+// LinkScope classifies a link relative to the page it appears on.
+type LinkScope string
+
+const (
+	// LinkInternal points to the same host, ignoring a leading "www.".
+	LinkInternal LinkScope = "internal"
+	// LinkSameSite points to another host of the same registrable
+	// domain, such as docs.example.com from www.example.com.
+	LinkSameSite LinkScope = "same-site"
+	LinkExternal LinkScope = "external"
+)
+
+// Link is a hyperlink found on a page.
+type Link struct {
+	URL  string `json:"url"`
+	Text string `json:"text,omitempty"`
+	Rel  string `json:"rel,omitempty"`
+	// Section is the text of the closest heading before the link.
+	Section string    `json:"section,omitempty"`
+	Scope   LinkScope `json:"scope"`
+	// Inline is set when the link is also rendered in the page text.
+	Inline bool `json:"inline,omitempty"`
+}
+
+// extractLinks returns the http(s) links of doc in document order, resolved
+// against base, without fragments and deduplicated by URL. A duplicate
+// only contributes its anchor text when the first occurrence had none.
+func extractLinks(doc *goquery.Document, base *url.URL) []Link {
+	var links []Link
+	index := make(map[string]int)
+	section := ""
+	doc.Find("h1, h2, h3, h4, h5, h6, a[href]").Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) != "a" {
+			section = singleLine(s.Text())
+			return
+		}
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		u, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+		if base != nil {
+			u = base.ResolveReference(u)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return
+		}
+		u.Fragment = ""
+		abs := u.String()
+		text := singleLine(s.Text())
+		if text == "" {
+			text = singleLine(s.AttrOr("title", s.AttrOr("aria-label", "")))
+		}
+		if i, ok := index[abs]; ok {
+			if links[i].Text == "" {
+				links[i].Text = text
+			}
+			return
+		}
+		if len(links) >= maxLinks {
+			return
+		}
+		index[abs] = len(links)
+		links = append(links, Link{
+			URL:     abs,
+			Text:    text,
+			Rel:     strings.Join(strings.Fields(s.AttrOr("rel", "")), " "),
+			Section: section,
+			Scope:   linkScope(base, u),
+		})
+	})
+	return links
+}
+
+func linkScope(base, u *url.URL) LinkScope {
+	if base == nil {
+		return LinkExternal
+	}
+	if sameHost(base, u) {
+		return LinkInternal
+	}
+	a, errA := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(base.Hostname()))
+	b, errB := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(u.Hostname()))
+	if errA == nil && errB == nil && a == b {
+		return LinkSameSite
+	}
+	return LinkExternal
+}
+
+// LinkFilter selects which links of a page are shown.
+type LinkFilter string
+
+const (
+	LinksAll LinkFilter = "all"
+	// LinksInternal keeps internal and same-site links.
+	LinksInternal LinkFilter = "internal"
+	LinksNone     LinkFilter = "none"
+)
+
+// ParseLinkFilter validates s as a LinkFilter. An empty string selects
+// LinksAll.
+func ParseLinkFilter(s string) (LinkFilter, error) {
+	switch LinkFilter(s) {
+	case "", LinksAll:
+		return LinksAll, nil
+	case LinksInternal, LinksNone:
+		return LinkFilter(s), nil
+	}
+	return "", fmt.Errorf("invalid links %q: must be %q, %q or %q", s, LinksNone, LinksInternal, LinksAll)
+}
+
+// Apply returns the links kept by f, in their original order.
+func (f LinkFilter) Apply(links []Link) []Link {
+	switch f {
+	case LinksNone:
+		return nil
+	case LinksInternal:
+		var out []Link
+		for _, l := range links {
+			if l.Scope != LinkExternal {
+				out = append(out, l)
+			}
+		}
+		return out
+	}
+	return links
+}
+
+// LinkStyle selects how links inside the page text are rendered.
+type LinkStyle string
+
+const (
+	// LinkStylePlain keeps the anchor text and drops the URL.
+	LinkStylePlain LinkStyle = "plain"
+	// LinkStyleInline renders [text](url) links.
+	LinkStyleInline LinkStyle = "inline"
+	// LinkStyleReference renders [text][n] links, with the URLs listed
+	// once at the end of the text.
+	LinkStyleReference LinkStyle = "reference"
+)
+
+// ParseLinkStyle validates s as a LinkStyle. An empty string selects
+// LinkStylePlain.
+func ParseLinkStyle(s string) (LinkStyle, error) {
+	switch LinkStyle(s) {
+	case "", LinkStylePlain:
+		return LinkStylePlain, nil
+	case LinkStyleInline, LinkStyleReference:
+		return LinkStyle(s), nil
+	}
+	return "", fmt.Errorf("invalid link_style %q: must be %q, %q or %q", s, LinkStylePlain, LinkStyleInline, LinkStyleReference)
+}
+
+// refScheme marks the hrefs rewritten for LinkStyleReference so they can be
+// turned into reference links after markdown conversion.
+const refScheme = "web-mcp-ref:"
+
+var (
+	refLink       = regexp.MustCompile(`\]\(` + refScheme + `(\d+)\)`)
+	refUse        = regexp.MustCompile(`\]\[(\d+)\]`)
+	refDefinition = regexp.MustCompile(`(?m)^\[(\d+)\]: (\S+)$`)
+)
+
+// rewriteAnchors prepares the anchors of doc for markdown conversion in the
+// given style. Anchors are unwrapped so their text survives, unless the
+// style keeps links and they have an href to an http(s) URL, in which case
+// the href is resolved against base. It returns the URLs kept in the text,
+// in order of first use.
+func rewriteAnchors(doc *goquery.Document, base *url.URL, style LinkStyle) []string {
+	var kept []string
+	index := make(map[string]int)
+	doc.Find("a").Each(func(_ int, s *goquery.Selection) {
+		href, hasHref := s.Attr("href")
+		u, err := url.Parse(strings.TrimSpace(href))
+		if err == nil && base != nil {
+			u = base.ResolveReference(u)
+		}
+		if style == LinkStylePlain || !hasHref || err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			s.ReplaceWithSelection(s.Contents())
+			return
+		}
+		abs := u.String()
+		i, ok := index[abs]
+		if !ok {
+			i = len(kept)
+			index[abs] = i
+			kept = append(kept, abs)
+		}
+		if style == LinkStyleReference {
+			s.SetAttr("href", refScheme+strconv.Itoa(i+1))
+			s.RemoveAttr("title")
+		} else {
+			s.SetAttr("href", abs)
+		}
+	})
+	return kept
+}
+
+// referenceLinks turns the placeholder links left by rewriteAnchors into
+// [text][n] references and appends their definitions to markdown.
+func referenceLinks(markdown string, urls []string) string {
+	if len(urls) == 0 {
+		return markdown
+	}
+	var sb strings.Builder
+	sb.WriteString(refLink.ReplaceAllString(markdown, "][$1]"))
+	sb.WriteString("\n")
+	for i, u := range urls {
+		sb.WriteString(fmt.Sprintf("\n[%d]: %s", i+1, u))
+	}
+	return sb.String()
+}
+
+// withReferences appends to part, an excerpt of text, the definitions of
+// the reference links it uses that it does not already contain.
+func withReferences(part, text string) string {
+	defined := make(map[string]bool)
+	for _, m := range refDefinition.FindAllStringSubmatch(part, -1) {
+		defined[m[1]] = true
+	}
+	urls := make(map[string]string)
+	for _, m := range refDefinition.FindAllStringSubmatch(text, -1) {
+		urls[m[1]] = m[2]
+	}
+	var sb strings.Builder
+	for _, m := range refUse.FindAllStringSubmatch(part, -1) {
+		n := m[1]
+		if u, ok := urls[n]; ok && !defined[n] {
+			defined[n] = true
+			sb.WriteString(fmt.Sprintf("\n[%s]: %s", n, u))
+		}
+	}
+	if sb.Len() == 0 {
+		return part
+	}
+	return part + "\n" + sb.String()
+}
+
+// markInline flags the links whose URL, without fragment, appears in the
+// page text.
+func markInline(links []Link, inline []string) {
+	seen := make(map[string]bool, len(inline))
+	for _, raw := range inline {
+		if u, err := url.Parse(raw); err == nil {
+			u.Fragment = ""
+			seen[u.String()] = true
+		}
+	}
+	for i := range links {
+		links[i].Inline = seen[links[i].URL]
+	}
+}
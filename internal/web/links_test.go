@@ -0,0 +1,226 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func parseDoc(t *testing.T, page string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestExtractLinks(t *testing.T) {
+	doc := parseDoc(t, `<html><body>
+<a href="/start">Start</a>
+<h2>Install   guide</h2>
+<a href="/docs#intro" rel="nofollow  noopener"><img alt=""></a>
+<a href="/docs#usage">Docs</a>
+<a href="https://docs.example.com/api" title="API reference"></a>
+<a href="mailto:me@example.com">Mail</a>
+<a href="javascript:void(0)">Click</a>
+<a href="#top">Top</a>
+<h3>Elsewhere</h3>
+<a href="//other.example.org/x" aria-label="Other site">  </a>
+<a href="http://[bad">Broken</a>
+</body></html>`)
+	got := extractLinks(doc, mustParseURL(t, "https://www.example.com/guide/page"))
+	want := []Link{
+		{URL: "https://www.example.com/start", Text: "Start", Scope: LinkInternal},
+		{URL: "https://www.example.com/docs", Text: "Docs", Rel: "nofollow noopener", Section: "Install guide", Scope: LinkInternal},
+		{URL: "https://docs.example.com/api", Text: "API reference", Section: "Install guide", Scope: LinkSameSite},
+		{URL: "https://www.example.com/guide/page", Text: "Top", Section: "Install guide", Scope: LinkInternal},
+		{URL: "https://other.example.org/x", Text: "Other site", Section: "Elsewhere", Scope: LinkExternal},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestExtractLinksKeepsFirstText(t *testing.T) {
+	doc := parseDoc(t, `<a href="/a">First</a><a href="/a#x">Second</a>`)
+	got := extractLinks(doc, mustParseURL(t, "https://example.com/"))
+	if len(got) != 1 || got[0].Text != "First" {
+		t.Errorf("got %+v, want one link with the first text", got)
+	}
+}
+
+func TestExtractLinksCapsAtMaxLinks(t *testing.T) {
+	var sb strings.Builder
+	for i := range maxLinks + 10 {
+		fmt.Fprintf(&sb, `<a href="/p%d">Page %d</a>`, i, i)
+	}
+	// Duplicates past the cap still fill in missing text.
+	sb.WriteString(`<a href="/p0">Again</a>`)
+	got := extractLinks(parseDoc(t, sb.String()), mustParseURL(t, "https://example.com/"))
+	if len(got) != maxLinks {
+		t.Fatalf("got %d links, want %d", len(got), maxLinks)
+	}
+	if first, last := got[0], got[maxLinks-1]; first.URL != "https://example.com/p0" || last.URL != fmt.Sprintf("https://example.com/p%d", maxLinks-1) {
+		t.Errorf("kept %s to %s, want the first %d in document order", first.URL, last.URL, maxLinks)
+	}
+}
+
+func TestLinkScope(t *testing.T) {
+	for _, tc := range []struct {
+		base, link string
+		want       LinkScope
+	}{
+		{"https://example.com/", "https://example.com/a", LinkInternal},
+		{"https://www.example.com/", "https://example.com/a", LinkInternal},
+		{"https://example.com/", "https://www.example.com/a", LinkInternal},
+		{"https://example.com/", "http://EXAMPLE.com:8080/a", LinkInternal},
+		{"https://www.example.com/", "https://docs.example.com/a", LinkSameSite},
+		{"https://a.b.example.co.uk/", "https://c.example.co.uk/", LinkSameSite},
+		{"https://example.co.uk/", "https://other.co.uk/", LinkExternal},
+		{"https://user.github.io/", "https://other.github.io/", LinkExternal},
+		{"https://example.com/", "https://example.org/", LinkExternal},
+		{"https://example.com/", "https://notexample.com/", LinkExternal},
+	} {
+		if got := linkScope(mustParseURL(t, tc.base), mustParseURL(t, tc.link)); got != tc.want {
+			t.Errorf("linkScope(%s, %s) = %s, want %s", tc.base, tc.link, got, tc.want)
+		}
+	}
+	if got := linkScope(nil, mustParseURL(t, "https://example.com/")); got != LinkExternal {
+		t.Errorf("linkScope without a base = %s, want external", got)
+	}
+}
+
+func TestLinkFilter(t *testing.T) {
+	links := []Link{
+		{URL: "https://example.com/a", Scope: LinkInternal},
+		{URL: "https://other.example/", Scope: LinkExternal},
+		{URL: "https://docs.example.com/", Scope: LinkSameSite},
+	}
+	for _, tc := range []struct {
+		filter string
+		want   []Link
+	}{
+		{"", links},
+		{"all", links},
+		{"internal", []Link{links[0], links[2]}},
+		{"none", nil},
+	} {
+		f, err := ParseLinkFilter(tc.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.Apply(links); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: got %+v, want %+v", tc.filter, got, tc.want)
+		}
+	}
+	if _, err := ParseLinkFilter("external"); err == nil {
+		t.Error("ParseLinkFilter accepted an unknown filter")
+	}
+}
+
+const linkStylePage = `<html><body><main>
+<p>Read <a href="/docs/guide" title="The guide">the guide</a> or <a href="mailto:help@example.com">mail us</a>.</p>
+<p>See <a href="https://other.example.org/x#part">elsewhere</a> and <a href="guide">the guide again</a>.</p>
+</main></body></html>`
+
+func TestLinkStyles(t *testing.T) {
+	for _, tc := range []struct {
+		style LinkStyle
+		want  string
+	}{
+		{LinkStylePlain, "Read the guide or mail us.\n\nSee elsewhere and the guide again."},
+		{LinkStyleInline, "Read [the guide](https://example.com/docs/guide \"The guide\") or mail us.\n\nSee [elsewhere](https://other.example.org/x#part) and [the guide again](https://example.com/docs/guide)."},
+		{LinkStyleReference, "Read [the guide][1] or mail us.\n\nSee [elsewhere][2] and [the guide again][1].\n\n[1]: https://example.com/docs/guide\n[2]: https://other.example.org/x#part"},
+	} {
+		t.Run(string(tc.style), func(t *testing.T) {
+			ps := summarizeHTML(t, linkStylePage, FetchOptions{Mode: ModeFull, LinkStyle: tc.style})
+			if ps.Text != tc.want {
+				t.Errorf("got\n%q\nwant\n%q", ps.Text, tc.want)
+			}
+			// Links shown in the text are flagged so the Links list can
+			// leave them out.
+			inline := map[string]bool{}
+			for _, l := range ps.Links {
+				inline[l.URL] = l.Inline
+			}
+			wantInline := tc.style != LinkStylePlain
+			if inline["https://example.com/docs/guide"] != wantInline || inline["https://other.example.org/x"] != wantInline {
+				t.Errorf("inline flags = %v, want all %v", inline, wantInline)
+			}
+		})
+	}
+}
+
+func TestRewriteAnchors(t *testing.T) {
+	page := `<a href="/a">A</a><a href=" /a ">A again</a><a href="ftp://x/">F</a><a>none</a><a href="https://b.example/">B</a>`
+	base := mustParseURL(t, "https://example.com/")
+	for _, tc := range []struct {
+		style LinkStyle
+		kept  []string
+		html  string
+	}{
+		{LinkStylePlain, nil, `AA againFnoneB`},
+		{LinkStyleInline, []string{"https://example.com/a", "https://b.example/"}, `<a href="https://example.com/a">A</a><a href="https://example.com/a">A again</a>Fnone<a href="https://b.example/">B</a>`},
+		{LinkStyleReference, []string{"https://example.com/a", "https://b.example/"}, `<a href="web-mcp-ref:1">A</a><a href="web-mcp-ref:1">A again</a>Fnone<a href="web-mcp-ref:2">B</a>`},
+	} {
+		doc := parseDoc(t, page)
+		kept := rewriteAnchors(doc, base, tc.style)
+		if !reflect.DeepEqual(kept, tc.kept) {
+			t.Errorf("%s: kept %q, want %q", tc.style, kept, tc.kept)
+		}
+		if html, _ := doc.Find("body").Html(); html != tc.html {
+			t.Errorf("%s: body %q, want %q", tc.style, html, tc.html)
+		}
+	}
+}
+
+func TestReferenceLinks(t *testing.T) {
+	got := referenceLinks("See [a](web-mcp-ref:1) and [b](web-mcp-ref:2), [a](web-mcp-ref:1).", []string{"https://a.example/", "https://b.example/"})
+	want := "See [a][1] and [b][2], [a][1].\n\n[1]: https://a.example/\n[2]: https://b.example/"
+	if got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+	if got := referenceLinks("No links.", nil); got != "No links." {
+		t.Errorf("without links got %q", got)
+	}
+}
+
+func TestWithReferences(t *testing.T) {
+	text := "Intro [a][1].\n\nMore [b][2] and [c][3].\n\n[1]: https://a.example/\n[2]: https://b.example/\n[3]: https://c.example/"
+	for _, tc := range []struct{ part, want string }{
+		{"More [b][2] and [c][3].", "More [b][2] and [c][3].\n\n[2]: https://b.example/\n[3]: https://c.example/"},
+		{"[c][3] then [c][3].", "[c][3] then [c][3].\n\n[3]: https://c.example/"},
+		{"No links.", "No links."},
+		{"Unknown [x][9].", "Unknown [x][9]."},
+		{"Tail [c][3].\n\n[3]: https://c.example/", "Tail [c][3].\n\n[3]: https://c.example/"},
+	} {
+		if got := withReferences(tc.part, text); got != tc.want {
+			t.Errorf("withReferences(%q) =\n%q\nwant\n%q", tc.part, got, tc.want)
+		}
+	}
+}
+
+func TestParseLinkStyle(t *testing.T) {
+	for in, want := range map[string]LinkStyle{"": LinkStylePlain, "plain": LinkStylePlain, "inline": LinkStyleInline, "reference": LinkStyleReference} {
+		if got, err := ParseLinkStyle(in); err != nil || got != want {
+			t.Errorf("ParseLinkStyle(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseLinkStyle("footnote"); err == nil {
+		t.Error("ParseLinkStyle accepted an unknown style")
+	}
+}
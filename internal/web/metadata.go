@@ -0,0 +1,269 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// maxStructuredItems and maxItemProperties bound the structured data
+	// kept per page so a product catalog does not flood the summary.
+	maxStructuredItems = 10
+	maxItemProperties  = 30
+	maxPropertyLength  = 300
+)
+
+// StructuredItem is one JSON-LD or microdata item, such as an Article,
+// Product or FAQPage, with its scalar properties flattened into dotted
+// paths like "author.name" or "mainEntity.0.acceptedAnswer.text".
+type StructuredItem struct {
+	Type       string            `json:"type"`
+	Source     string            `json:"source"` // "json-ld" or "microdata"
+	Properties map[string]string `json:"properties"`
+}
+
+// extractMetadata fills the metadata fields of ps from the document head,
+// OpenGraph and Twitter cards, JSON-LD and microdata. It must run before
+// scripts are removed, since JSON-LD lives in script elements.
+func extractMetadata(doc *goquery.Document, base *url.URL, ps *PageSummary) {
+	meta := func(selectors ...string) string {
+		for _, sel := range selectors {
+			if v := strings.TrimSpace(doc.Find(sel).First().AttrOr("content", "")); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	ps.Title = strings.TrimSpace(doc.Find("head > title").First().Text())
+	if ps.Title == "" {
+		ps.Title = meta(`meta[property="og:title"]`, `meta[name="twitter:title"]`)
+	}
+	ps.Description = meta(`meta[name=description]`, `meta[property="og:description"]`, `meta[name="twitter:description"]`)
+	ps.Author = meta(`meta[name=author]`, `meta[property="article:author"]`, `meta[name="twitter:creator"]`)
+	ps.Published = meta(`meta[property="article:published_time"]`, `meta[name="date"]`, `meta[itemprop=datePublished]`)
+	ps.Modified = meta(`meta[property="article:modified_time"]`, `meta[property="og:updated_time"]`, `meta[itemprop=dateModified]`)
+	ps.SiteName = meta(`meta[property="og:site_name"]`)
+	ps.Type = meta(`meta[property="og:type"]`)
+	ps.Image = resolve(base, meta(`meta[property="og:image"]`, `meta[name="twitter:image"]`))
+
+	ps.Canonical = resolve(base, strings.TrimSpace(doc.Find(`link[rel~=canonical]`).First().AttrOr("href", "")))
+	if ps.Canonical == "" {
+		ps.Canonical = resolve(base, meta(`meta[property="og:url"]`))
+	}
+	ps.Language = strings.TrimSpace(doc.Find("html").First().AttrOr("lang", ""))
+	if ps.Language == "" {
+		ps.Language = meta(`meta[http-equiv="content-language" i]`, `meta[property="og:locale"]`)
+	}
+
+	ps.StructuredData = append(jsonLDItems(doc), microdataItems(doc, base)...)
+	if len(ps.StructuredData) > maxStructuredItems {
+		ps.StructuredData = ps.StructuredData[:maxStructuredItems]
+	}
+
+	// Fill gaps from the structured data, which often carries what the
+	// meta tags leave out.
+	for _, it := range ps.StructuredData {
+		p := it.Properties
+		if ps.Author == "" {
+			ps.Author = firstNonEmpty(p["author.name"], p["author.0.name"], p["author"])
+		}
+		if ps.Published == "" {
+			ps.Published = p["datePublished"]
+		}
+		if ps.Modified == "" {
+			ps.Modified = p["dateModified"]
+		}
+		if ps.Title == "" {
+			ps.Title = firstNonEmpty(p["headline"], p["name"])
+		}
+	}
+}
+
+func resolve(base *url.URL, ref string) string {
+	if ref == "" || base == nil {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+func firstNonEmpty(vs ...string) string {
+	for _, v := range vs {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// jsonLDItems parses every JSON-LD script, expanding top-level arrays and
+// @graph lists into separate items.
+func jsonLDItems(doc *goquery.Document) []StructuredItem {
+	var items []StructuredItem
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var v any
+		if json.Unmarshal([]byte(s.Text()), &v) != nil {
+			return
+		}
+		var nodes []any
+		switch t := v.(type) {
+		case []any:
+			nodes = t
+		case map[string]any:
+			if graph, ok := t["@graph"].([]any); ok {
+				nodes = graph
+			} else {
+				nodes = []any{t}
+			}
+		}
+		for _, n := range nodes {
+			obj, ok := n.(map[string]any)
+			if !ok {
+				continue
+			}
+			props := make(map[string]string)
+			flatten("", obj, props)
+			if len(props) == 0 {
+				continue
+			}
+			items = append(items, StructuredItem{Type: jsonLDType(obj["@type"]), Source: "json-ld", Properties: props})
+		}
+	})
+	return items
+}
+
+func jsonLDType(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []any:
+		var types []string
+		for _, x := range t {
+			if s, ok := x.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return strings.Join(types, ",")
+	}
+	return ""
+}
+
+// flatten stores the scalar leaves of v in props under dotted keys,
+// skipping JSON-LD keywords and stopping at maxItemProperties.
+func flatten(prefix string, v any, props map[string]string) {
+	if len(props) >= maxItemProperties {
+		return
+	}
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			if !strings.HasPrefix(k, "@") {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flatten(join(k), t[k], props)
+		}
+	case []any:
+		for i, x := range t {
+			flatten(join(fmt.Sprint(i)), x, props)
+		}
+	case string:
+		if s := singleLine(t); s != "" {
+			props[prefix] = truncateProperty(s)
+		}
+	case float64, bool:
+		props[prefix] = fmt.Sprint(t)
+	}
+}
+
+func truncateProperty(s string) string {
+	if r := []rune(s); len(r) > maxPropertyLength {
+		return string(r[:maxPropertyLength]) + "…"
+	}
+	return s
+}
+
+// microdataItems reads top-level itemscope elements. Properties of nested
+// items are prefixed with the property that holds them.
+func microdataItems(doc *goquery.Document, base *url.URL) []StructuredItem {
+	var items []StructuredItem
+	doc.Find("[itemscope]").Each(func(_ int, s *goquery.Selection) {
+		if s.ParentsFiltered("[itemscope]").Length() > 0 {
+			return
+		}
+		props := make(map[string]string)
+		microdataProps(s, "", base, props)
+		if len(props) == 0 {
+			return
+		}
+		items = append(items, StructuredItem{Type: path.Base(s.AttrOr("itemtype", "")), Source: "microdata", Properties: props})
+	})
+	return items
+}
+
+func microdataProps(scope *goquery.Selection, prefix string, base *url.URL, props map[string]string) {
+	scope.Find("[itemprop]").Each(func(_ int, p *goquery.Selection) {
+		// Only properties whose nearest item is scope belong to it.
+		if owner := p.ParentsFiltered("[itemscope]").First(); !owner.IsSelection(scope) || len(props) >= maxItemProperties {
+			return
+		}
+		for _, name := range strings.Fields(p.AttrOr("itemprop", "")) {
+			key := name
+			if prefix != "" {
+				key = prefix + "." + name
+			}
+			if _, nested := p.Attr("itemscope"); nested {
+				microdataProps(p, key, base, props)
+				continue
+			}
+			if _, seen := props[key]; seen {
+				continue
+			}
+			if v := microdataValue(p, base); v != "" {
+				props[key] = truncateProperty(v)
+			}
+		}
+	})
+}
+
+func microdataValue(p *goquery.Selection, base *url.URL) string {
+	switch goquery.NodeName(p) {
+	case "meta":
+		return strings.TrimSpace(p.AttrOr("content", ""))
+	case "a", "link", "area":
+		return resolve(base, strings.TrimSpace(p.AttrOr("href", "")))
+	case "img", "audio", "video", "source", "embed", "iframe":
+		return resolve(base, strings.TrimSpace(p.AttrOr("src", "")))
+	case "time":
+		if v, ok := p.Attr("datetime"); ok {
+			return strings.TrimSpace(v)
+		}
+	case "data", "meter":
+		if v, ok := p.Attr("value"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	if v, ok := p.Attr("content"); ok {
+		return strings.TrimSpace(v)
+	}
+	return singleLine(p.Text())
+}
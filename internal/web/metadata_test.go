@@ -0,0 +1,186 @@
+package web
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func metadataOf(t *testing.T, page string) *PageSummary {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/blog/post")
+	ps := &PageSummary{}
+	extractMetadata(doc, base, ps)
+	return ps
+}
+
+func TestMetadataFromMetaTags(t *testing.T) {
+	ps := metadataOf(t, `<html lang="en-GB"><head>
+<title> The Title </title>
+<meta name="description" content="Plain description">
+<meta property="og:description" content="OpenGraph description">
+<meta name="author" content="Jane Doe">
+<meta property="article:published_time" content="2024-03-01T10:00:00Z">
+<meta property="article:modified_time" content="2024-03-02">
+<meta property="og:site_name" content="Example Blog">
+<meta property="og:type" content="article">
+<meta property="og:image" content="/img/cover.png">
+<link rel="alternate canonical" href="../canonical">
+</head><body></body></html>`)
+	want := PageSummary{
+		Title:       "The Title",
+		Description: "Plain description",
+		Author:      "Jane Doe",
+		Published:   "2024-03-01T10:00:00Z",
+		Modified:    "2024-03-02",
+		SiteName:    "Example Blog",
+		Type:        "article",
+		Image:       "https://example.com/img/cover.png",
+		Canonical:   "https://example.com/canonical",
+		Language:    "en-GB",
+	}
+	if !reflect.DeepEqual(*ps, want) {
+		t.Errorf("got  %+v\nwant %+v", *ps, want)
+	}
+}
+
+func TestMetadataFallsBackToSocialCards(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		head string
+		want PageSummary
+	}{
+		{
+			name: "opengraph",
+			head: `<meta property="og:title" content="OG title">
+<meta property="og:description" content="OG description">
+<meta property="og:url" content="https://example.com/og">
+<meta property="og:locale" content="fr_FR">
+<meta property="og:updated_time" content="2024-05-06">`,
+			want: PageSummary{Title: "OG title", Description: "OG description", Canonical: "https://example.com/og", Language: "fr_FR", Modified: "2024-05-06"},
+		},
+		{
+			name: "twitter",
+			head: `<meta name="twitter:title" content="Card title">
+<meta name="twitter:description" content="Card description">
+<meta name="twitter:creator" content="@jane">
+<meta name="twitter:image" content="cover.jpg">`,
+			want: PageSummary{Title: "Card title", Description: "Card description", Author: "@jane", Image: "https://example.com/blog/cover.jpg"},
+		},
+		{
+			name: "opengraph before twitter",
+			head: `<meta name="twitter:title" content="Card title"><meta property="og:title" content="OG title">
+<meta name="twitter:image" content="/card.png"><meta property="og:image" content="https://cdn.example.org/og.png">`,
+			want: PageSummary{Title: "OG title", Image: "https://cdn.example.org/og.png"},
+		},
+		{
+			name: "content language header",
+			head: `<meta http-equiv="Content-Language" content="de"><meta property="og:locale" content="fr_FR">`,
+			want: PageSummary{Language: "de"},
+		},
+		{
+			name: "blank values are skipped",
+			head: `<title> </title><meta name="description" content="  "><meta property="og:description" content="Used">`,
+			want: PageSummary{Description: "Used"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := metadataOf(t, "<html><head>"+tc.head+"</head><body></body></html>")
+			if !reflect.DeepEqual(*ps, tc.want) {
+				t.Errorf("got  %+v\nwant %+v", *ps, tc.want)
+			}
+		})
+	}
+}
+
+func TestMetadataDates(t *testing.T) {
+	for _, tc := range []struct {
+		name                string
+		page                string
+		published, modified string
+	}{
+		{
+			name:      "meta date",
+			page:      `<head><meta name="date" content="2023-01-02"></head>`,
+			published: "2023-01-02",
+		},
+		{
+			name:      "itemprop meta",
+			page:      `<head><meta itemprop="datePublished" content="2023-01-02"><meta itemprop="dateModified" content="2023-02-03"></head>`,
+			published: "2023-01-02", modified: "2023-02-03",
+		},
+		{
+			name:      "json-ld",
+			page:      `<head><script type="application/ld+json">{"@type":"NewsArticle","datePublished":"2022-12-31T23:59:00+01:00","dateModified":"2023-01-01"}</script></head>`,
+			published: "2022-12-31T23:59:00+01:00", modified: "2023-01-01",
+		},
+		{
+			name:      "microdata time",
+			page:      `<body><article itemscope itemtype="https://schema.org/Article"><time itemprop="datePublished" datetime="2021-06-07">7 June</time></article></body>`,
+			published: "2021-06-07",
+		},
+		{
+			name:      "meta tags win over structured data",
+			page:      `<head><meta property="article:published_time" content="2020-01-01"><script type="application/ld+json">{"datePublished":"1999-01-01"}</script></head>`,
+			published: "2020-01-01",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := metadataOf(t, "<html>"+tc.page+"</html>")
+			if ps.Published != tc.published || ps.Modified != tc.modified {
+				t.Errorf("published %q, modified %q; want %q, %q", ps.Published, ps.Modified, tc.published, tc.modified)
+			}
+		})
+	}
+}
+
+func TestMetadataResolvesRelativeURLs(t *testing.T) {
+	for _, tc := range []struct{ ref, want string }{
+		{"/a", "https://example.com/a"},
+		{"a", "https://example.com/blog/a"},
+		{"../a", "https://example.com/a"},
+		{"//cdn.example.org/a", "https://cdn.example.org/a"},
+		{"http://other.example/a", "http://other.example/a"},
+		{"http://[bad", ""},
+	} {
+		ps := metadataOf(t, `<html><head><link rel="canonical" href="`+tc.ref+`"><meta property="og:image" content="`+tc.ref+`"></head></html>`)
+		if ps.Canonical != tc.want || ps.Image != tc.want {
+			t.Errorf("%q: canonical %q, image %q; want %q", tc.ref, ps.Canonical, ps.Image, tc.want)
+		}
+	}
+}
+
+func TestStructuredData(t *testing.T) {
+	ps := metadataOf(t, `<html><head>
+<script type="application/ld+json">{"@context":"https://schema.org","@graph":[
+  {"@type":"WebSite","name":"Example"},
+  {"@type":["Article","BlogPosting"],"headline":"Graph headline","author":[{"@type":"Person","name":"Ann"}],"wordCount":1200}
+]}</script>
+<script type="application/ld+json">not json</script>
+</head><body>
+<div itemscope itemtype="https://schema.org/Product">
+  <span itemprop="name">Widget</span>
+  <a itemprop="url" href="/widget">link</a>
+  <div itemprop="offers" itemscope itemtype="https://schema.org/Offer"><data itemprop="price" value="9.99">$9.99</data></div>
+</div>
+</body></html>`)
+	want := []StructuredItem{
+		{Type: "WebSite", Source: "json-ld", Properties: map[string]string{"name": "Example"}},
+		{Type: "Article,BlogPosting", Source: "json-ld", Properties: map[string]string{"headline": "Graph headline", "author.0.name": "Ann", "wordCount": "1200"}},
+		{Type: "Product", Source: "microdata", Properties: map[string]string{"name": "Widget", "url": "https://example.com/widget", "offers.price": "9.99"}},
+	}
+	if !reflect.DeepEqual(ps.StructuredData, want) {
+		t.Errorf("got  %+v\nwant %+v", ps.StructuredData, want)
+	}
+	// Gaps in the meta tags are filled from the structured data.
+	if ps.Title != "Example" || ps.Author != "Ann" {
+		t.Errorf("title %q, author %q; want them taken from the structured data", ps.Title, ps.Author)
+	}
+}
@@ -0,0 +1,195 @@
+package web
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Heading is an entry of a page outline.
+type Heading struct {
+	Level int    `json:"level"`
+	Title string `json:"title"`
+	// Anchor is the heading's id on the page, or a slug of its title when
+	// it has none. Anchors are unique within a page.
+	Anchor string `json:"anchor"`
+	// Offset is the position of the heading line in PageSummary.Text, in
+	// characters (runes).
+	Offset int `json:"offset"`
+}
+
+// htmlHeading is a heading found in the document before markdown
+// conversion, used to recover its id.
+type htmlHeading struct {
+	level int
+	key   string
+	id    string
+}
+
+// collectHeadings returns the h1-h6 elements of doc in document order. The
+// id is taken from the heading itself, a descendant, or an enclosing
+// section whose first heading it is.
+func collectHeadings(doc *goquery.Document) []htmlHeading {
+	var out []htmlHeading
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(_ int, s *goquery.Selection) {
+		id := s.AttrOr("id", "")
+		if id == "" {
+			id = s.Find("[id]").First().AttrOr("id", "")
+		}
+		if id == "" {
+			id = s.Find("a[name]").First().AttrOr("name", "")
+		}
+		if id == "" {
+			if p := s.Parent(); goquery.NodeName(p) == "section" && p.Find("h1, h2, h3, h4, h5, h6").First().IsSelection(s) {
+				id = p.AttrOr("id", "")
+			}
+		}
+		out = append(out, htmlHeading{
+			level: int(goquery.NodeName(s)[1] - '0'),
+			key:   headingKey(s.Text()),
+			id:    strings.TrimSpace(id),
+		})
+	})
+	return out
+}
+
+var (
+	atxHeading   = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+	fence        = regexp.MustCompile("^ {0,3}(```|~~~)")
+	markdownLink = regexp.MustCompile(`\]\([^)]*\)|\]\[\d+\]`)
+	markdownMark = regexp.MustCompile(`\\([\\!"#$%&'()*+,./:;<=>?@\[\]^_{|}~-])|[*_` + "`" + `\[\]]`)
+)
+
+// buildOutline lists the ATX headings of text outside code fences. Anchors
+// come from the matching entry of headings when there is one, in order,
+// and from the title otherwise.
+func buildOutline(text string, headings []htmlHeading) []Heading {
+	var outline []Heading
+	used := make(map[string]bool)
+	next := 0
+	offset := 0
+	inFence := ""
+	for _, line := range strings.SplitAfter(text, "\n") {
+		start := offset
+		offset += len([]rune(line))
+		line = strings.TrimRight(line, "\r\n")
+		if m := fence.FindStringSubmatch(line); m != nil {
+			if inFence == "" {
+				inFence = m[1]
+			} else if inFence == m[1] {
+				inFence = ""
+			}
+			continue
+		}
+		m := atxHeading.FindStringSubmatch(line)
+		if inFence != "" || m == nil {
+			continue
+		}
+		title := singleLine(markdownMark.ReplaceAllString(markdownLink.ReplaceAllString(m[2], "]"), "$1"))
+		if title == "" {
+			continue
+		}
+		h := Heading{Level: len(m[1]), Title: title, Offset: start}
+		key := headingKey(title)
+		for i := next; i < len(headings); i++ {
+			if headings[i].level == h.Level && headings[i].key == key {
+				h.Anchor = headings[i].id
+				next = i + 1
+				break
+			}
+		}
+		if h.Anchor == "" || used[h.Anchor] {
+			h.Anchor = uniqueSlug(slugify(title), used)
+		}
+		used[h.Anchor] = true
+		outline = append(outline, h)
+	}
+	return outline
+}
+
+// headingKey reduces a heading title to its lowercase letters and digits
+// so HTML and markdown renderings of it compare equal.
+func headingKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// slugify turns a title into an anchor the way GitHub does: lowercase,
+// spaces become hyphens and punctuation is dropped.
+func slugify(title string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			sb.WriteRune(r)
+		case unicode.IsSpace(r):
+			sb.WriteRune('-')
+		}
+	}
+	if sb.Len() == 0 {
+		return "section"
+	}
+	return sb.String()
+}
+
+func uniqueSlug(slug string, used map[string]bool) string {
+	if !used[slug] {
+		return slug
+	}
+	for i := 1; ; i++ {
+		if s := fmt.Sprintf("%s-%d", slug, i); !used[s] {
+			return s
+		}
+	}
+}
+
+// Section returns the text under the heading whose anchor or title is
+// name, up to the next heading of the same or a higher level. Anchors match
+// exactly, with or without a leading "#"; titles match case-insensitively,
+// first exactly and then by substring. Reference links used in the section
+// keep their definitions, which otherwise only appear at the end of Text.
+func (ps *PageSummary) Section(name string) (Heading, string, error) {
+	name = strings.TrimSpace(name)
+	idx := ps.findHeading(name)
+	if idx < 0 {
+		if len(ps.Outline) == 0 {
+			return Heading{}, "", fmt.Errorf("section %q not found: the page has no headings", name)
+		}
+		return Heading{}, "", fmt.Errorf("section %q not found; request the outline to list the available sections", name)
+	}
+	h := ps.Outline[idx]
+	runes := []rune(ps.Text)
+	end := len(runes)
+	for _, next := range ps.Outline[idx+1:] {
+		if next.Level <= h.Level {
+			end = next.Offset
+			break
+		}
+	}
+	section := strings.TrimSpace(string(runes[min(h.Offset, end):end]))
+	return h, withReferences(section, ps.Text), nil
+}
+
+func (ps *PageSummary) findHeading(name string) int {
+	anchor := strings.TrimPrefix(name, "#")
+	lower := strings.ToLower(name)
+	matches := []func(Heading) bool{
+		func(h Heading) bool { return h.Anchor == anchor },
+		func(h Heading) bool { return strings.EqualFold(h.Title, name) },
+		func(h Heading) bool { return strings.Contains(strings.ToLower(h.Title), lower) },
+	}
+	for _, match := range matches {
+		if i := slices.IndexFunc(ps.Outline, match); i >= 0 {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"strings"
+	"testing"
+)
+
+const outlinePage = `<html><body>
+<h1 id="top">Guide</h1><p>See <a href="/intro">the intro</a>.</p>
+<section id="install"><h2>Install</h2><p>Get it from <a href="https://pkg.example.org/">the registry</a>, then read <a href="/intro">the intro</a>.</p>
+<pre><code># not a heading
+</code></pre>
+<h3>On Linux</h3><p>Use apt.</p></section>
+<h2>Usage</h2><p>Run <a href="/run">run</a>.</p>
+<h2>Usage</h2><p>Again.</p>
+</body></html>`
+
+func TestOutline(t *testing.T) {
+	ps := summarizeHTML(t, outlinePage, FetchOptions{Mode: ModeFull})
+	want := []struct {
+		level         int
+		title, anchor string
+	}{
+		{1, "Guide", "top"},
+		{2, "Install", "install"},
+		{3, "On Linux", "on-linux"},
+		{2, "Usage", "usage"},
+		{2, "Usage", "usage-1"},
+	}
+	if len(ps.Outline) != len(want) {
+		t.Fatalf("outline = %+v", ps.Outline)
+	}
+	runes := []rune(ps.Text)
+	for i, w := range want {
+		h := ps.Outline[i]
+		if h.Level != w.level || h.Title != w.title || h.Anchor != w.anchor {
+			t.Errorf("heading %d = %+v, want %+v", i, h, w)
+		}
+		if !strings.HasPrefix(string(runes[h.Offset:]), strings.Repeat("#", h.Level)+" "+h.Title) {
+			t.Errorf("heading %d offset %d points at %q", i, h.Offset, string(runes[h.Offset:min(h.Offset+20, len(runes))]))
+		}
+	}
+}
+
+func TestSection(t *testing.T) {
+	ps := summarizeHTML(t, outlinePage, FetchOptions{Mode: ModeFull})
+	for _, tc := range []struct {
+		name, want, not string
+	}{
+		{name: "#install", want: "Use apt.", not: "Usage"},
+		{name: "on linux", want: "Use apt.", not: "registry"},
+		{name: "usage-1", want: "Again.", not: "Run"},
+		{name: "Usage", want: "Run", not: "Again."},
+	} {
+		_, text, err := ps.Section(tc.name)
+		if err != nil {
+			t.Fatalf("Section(%q): %v", tc.name, err)
+		}
+		if !strings.Contains(text, tc.want) || strings.Contains(text, tc.not) {
+			t.Errorf("Section(%q) = %q", tc.name, text)
+		}
+	}
+	if _, _, err := ps.Section("missing"); err == nil {
+		t.Error("Section(missing) succeeded")
+	}
+}
+
+func TestSectionKeepsReferenceDefinitions(t *testing.T) {
+	ps := summarizeHTML(t, outlinePage, FetchOptions{Mode: ModeFull, LinkStyle: LinkStyleReference})
+	_, text, err := ps.Section("install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"[the registry][2]", "[the intro][1]",
+		"[1]: https://example.com/intro", "[2]: https://pkg.example.org/",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("section lacks %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "[3]:") {
+		t.Errorf("section defines a reference it does not use:\n%s", text)
+	}
+
+	// The last section already ends with every definition.
+	_, text, err = ps.Section("usage-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(text, "[1]: ") != 1 {
+		t.Errorf("last section repeats definitions:\n%s", text)
+	}
+}
@@ -0,0 +1,328 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// MaxPDFSize is the largest PDF document the fetcher downloads. Unlike HTML,
+// a PDF cannot be parsed once trimmed, so larger documents are rejected.
+const MaxPDFSize = 10 * 1024 * 1024 // 10MB
+
+// pdfMagic starts every PDF document.
+const pdfMagic = "%PDF-"
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// isPDF reports whether a response with the given content type and body is a
+// PDF document.
+func isPDF(contentType string, body []byte) bool {
+	return strings.Contains(strings.ToLower(contentType), "application/pdf") || bytes.HasPrefix(body, []byte(pdfMagic))
+}
+
+// expectPDF reports whether a response is likely to be a PDF before its body
+// has been read in full, so the larger size limit can be applied. head is
+// the start of the body, which identifies PDFs served under a generic
+// content type.
+func expectPDF(contentType string, u *url.URL, head []byte) bool {
+	return isPDF(contentType, head) || strings.HasSuffix(strings.ToLower(u.Path), ".pdf")
+}
+
+// summarizePDF extracts the text of a PDF document as markdown with one
+// section per page, along with the document metadata.
+func summarizePDF(resp *response) (ps *PageSummary, err error) {
+	// The PDF parser panics on some malformed documents.
+	defer func() {
+		if r := recover(); r != nil {
+			ps, err = nil, fmt.Errorf("failed to parse PDF: %v", r)
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(resp.body), int64(len(resp.body)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+	info := r.Trailer().Key("Info")
+	pageCount := r.NumPage()
+
+	var sb strings.Builder
+	for i := 1; i <= pageCount; i++ {
+		p := r.Page(i)
+		if p.V.IsNull() {
+			continue
+		}
+		fmt.Fprintf(&sb, "## Page %d\n\n%s\n\n", i, cleanPDFText(pageText(p)))
+		if sb.Len() > resp.maxText {
+			break
+		}
+	}
+
+	out := strings.TrimSpace(sb.String())
+	if out == "" {
+		return nil, errors.New("no text could be extracted from the PDF (it may be scanned or image-only)")
+	}
+	if len(out) > resp.maxText {
+		out = strings.ToValidUTF8(out[:resp.maxText], "") + "... [document trimmed due to size]"
+	}
+
+	return &PageSummary{
+		URL:         resp.finalURL,
+		Title:       strings.TrimSpace(info.Key("Title").Text()),
+		Description: strings.TrimSpace(info.Key("Subject").Text()),
+		Author:      strings.TrimSpace(info.Key("Author").Text()),
+		PageCount:   pageCount,
+		Text:        out,
+	}, nil
+}
+
+// pageText lays out the text of a page as plain text. PDF content streams
+// position runs of glyphs individually, so spaces and line breaks are
+// inferred from the gaps between consecutive runs.
+func pageText(p pdf.Page) string {
+	var sb strings.Builder
+	var prev *textRun
+	for _, run := range pageRuns(p) {
+		if prev != nil {
+			size := math.Max(prev.size, 1)
+			dy := math.Abs(run.y - prev.y)
+			switch {
+			case dy > size*2:
+				sb.WriteString("\n\n")
+			case dy > size*0.5:
+				sb.WriteString("\n")
+			case run.x-prev.end > size*0.15 && !strings.HasSuffix(prev.s, " ") && !strings.HasPrefix(run.s, " "):
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString(run.s)
+		prev = &run
+	}
+	return sb.String()
+}
+
+// textRun is a string drawn at a single position on a page, in device
+// space. end is the x coordinate where the run stops.
+type textRun struct {
+	x, y, end, size float64
+	s               string
+}
+
+// pdfFont caches what the text walker needs from a font. Looking widths up
+// through pdf.Font resolves objects on every call, which is very slow on
+// documents using compressed object streams.
+type pdfFont struct {
+	enc    pdf.TextEncoding
+	first  int
+	widths []float64
+}
+
+// advance returns the horizontal advance of code in thousandths of the font
+// size. Fonts without a width table are assumed to use average-width glyphs.
+func (f *pdfFont) advance(code int) float64 {
+	if i := code - f.first; i >= 0 && i < len(f.widths) {
+		return f.widths[i]
+	}
+	return 500
+}
+
+// affine is a PDF transformation matrix [a b c d e f] applied to row vectors.
+type affine [6]float64
+
+var identity = affine{1, 0, 0, 1, 0, 0}
+
+// then returns the transformation applying m followed by n.
+func (m affine) then(n affine) affine {
+	return affine{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4], m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translate(tx, ty float64) affine { return affine{1, 0, 0, 1, tx, ty} }
+
+// pageRuns walks the content stream of p and returns its text runs in
+// drawing order.
+func pageRuns(p pdf.Page) []textRun {
+	if p.V.IsNull() || p.V.Key("Contents").Kind() == pdf.Null {
+		return nil
+	}
+	type state struct {
+		ctm                        affine
+		font                       *pdfFont
+		size, tc, tw, th, tl, rise float64
+	}
+	fonts := make(map[string]*pdfFont)
+	loadFont := func(name string) *pdfFont {
+		if f, ok := fonts[name]; ok {
+			return f
+		}
+		font := p.Font(name)
+		f := &pdfFont{enc: font.Encoder(), first: font.FirstChar(), widths: font.Widths()}
+		fonts[name] = f
+		return f
+	}
+
+	g := state{ctm: identity, th: 1}
+	var stack []state
+	tm, tlm := identity, identity
+	var runs []textRun
+
+	show := func(raw string) {
+		if g.font == nil || raw == "" {
+			return
+		}
+		trm := affine{g.size * g.th, 0, 0, g.size, 0, g.rise}.then(tm).then(g.ctm)
+		tx := 0.0
+		for i := 0; i < len(raw); i++ {
+			w := g.font.advance(int(raw[i]))/1000*g.size + g.tc
+			if raw[i] == ' ' {
+				w += g.tw
+			}
+			tx += w * g.th
+		}
+		end := translate(tx, 0).then(tm).then(g.ctm)
+		runs = append(runs, textRun{
+			x:    trm[4],
+			y:    trm[5],
+			end:  end[4],
+			size: math.Hypot(trm[2], trm[3]),
+			s:    strings.Map(printable, g.font.enc.Decode(raw)),
+		})
+		tm = translate(tx, 0).then(tm)
+	}
+	nextLine := func() {
+		tlm = translate(0, -g.tl).then(tlm)
+		tm = tlm
+	}
+
+	pdf.Interpret(p.V.Key("Contents"), func(stk *pdf.Stack, op string) {
+		n := stk.Len()
+		args := make([]pdf.Value, n)
+		for i := n - 1; i >= 0; i-- {
+			args[i] = stk.Pop()
+		}
+		matrixArg := func() (affine, bool) {
+			if len(args) != 6 {
+				return affine{}, false
+			}
+			var m affine
+			for i := range m {
+				m[i] = args[i].Float64()
+			}
+			return m, true
+		}
+		switch op {
+		case "q":
+			stack = append(stack, g)
+		case "Q":
+			if len(stack) > 0 {
+				g = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if m, ok := matrixArg(); ok {
+				g.ctm = m.then(g.ctm)
+			}
+		case "BT":
+			tm, tlm = identity, identity
+		case "Tf":
+			if len(args) == 2 {
+				g.font = loadFont(args[0].Name())
+				g.size = args[1].Float64()
+			}
+		case "Tc":
+			if len(args) == 1 {
+				g.tc = args[0].Float64()
+			}
+		case "Tw":
+			if len(args) == 1 {
+				g.tw = args[0].Float64()
+			}
+		case "Tz":
+			if len(args) == 1 {
+				g.th = args[0].Float64() / 100
+			}
+		case "TL":
+			if len(args) == 1 {
+				g.tl = args[0].Float64()
+			}
+		case "Ts":
+			if len(args) == 1 {
+				g.rise = args[0].Float64()
+			}
+		case "TD", "Td":
+			if len(args) == 2 {
+				if op == "TD" {
+					g.tl = -args[1].Float64()
+				}
+				tlm = translate(args[0].Float64(), args[1].Float64()).then(tlm)
+				tm = tlm
+			}
+		case "Tm":
+			if m, ok := matrixArg(); ok {
+				tm, tlm = m, m
+			}
+		case "T*":
+			nextLine()
+		case "Tj":
+			if len(args) == 1 {
+				show(args[0].RawString())
+			}
+		case "'":
+			if len(args) == 1 {
+				nextLine()
+				show(args[0].RawString())
+			}
+		case "\"":
+			if len(args) == 3 {
+				g.tw = args[0].Float64()
+				g.tc = args[1].Float64()
+				nextLine()
+				show(args[2].RawString())
+			}
+		case "TJ":
+			if len(args) != 1 {
+				return
+			}
+			for i := 0; i < args[0].Len(); i++ {
+				v := args[0].Index(i)
+				if v.Kind() == pdf.String {
+					show(v.RawString())
+					continue
+				}
+				tm = translate(-v.Float64()/1000*g.size*g.th, 0).then(tm)
+			}
+		}
+	})
+	return runs
+}
+
+// printable drops control characters and replacement runes that fonts
+// without a usable encoding produce.
+func printable(r rune) rune {
+	if r == utf8.RuneError || (unicode.IsControl(r) && r != '\n' && r != '\t') {
+		return -1
+	}
+	return r
+}
+
+// cleanPDFText trims trailing whitespace from every line and collapses runs
+// of blank lines.
+func cleanPDFText(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+	s = strings.Join(lines, "\n")
+	return strings.TrimSpace(blankLines.ReplaceAllString(s, "\n\n"))
+}
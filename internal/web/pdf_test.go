@@ -0,0 +1,85 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func readSamplePDF(t *testing.T) []byte {
+	t.Helper()
+	b, err := os.ReadFile("testdata/sample.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSummarizePDF(t *testing.T) {
+	ps, err := summarizePDF(&response{
+		finalURL: "https://example.com/sample.pdf",
+		body:     readSamplePDF(t),
+		maxText:  MaxResponseSize,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Title != "Sample Document" || ps.Author != "Jane Doe" || ps.Description != "A test fixture" {
+		t.Errorf("metadata = %q, %q, %q", ps.Title, ps.Author, ps.Description)
+	}
+	if ps.PageCount != 2 {
+		t.Errorf("PageCount = %d, want 2", ps.PageCount)
+	}
+	want := "## Page 1\n\n" +
+		"Hello PDF\n" +
+		"Second line\n\n" +
+		"Kerned\n" +
+		"Two words\n\n" +
+		"## Page 2\n\n" +
+		"Page two"
+	if ps.Text != want {
+		t.Errorf("Text =\n%s\nwant\n%s", ps.Text, want)
+	}
+}
+
+func TestSummarizePDFTrimsText(t *testing.T) {
+	ps, err := summarizePDF(&response{body: readSamplePDF(t), maxText: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(ps.Text, "## Page 1\n\nHello PDF") || !strings.HasSuffix(ps.Text, "[document trimmed due to size]") {
+		t.Errorf("Text = %q", ps.Text)
+	}
+	if strings.Contains(ps.Text, "Page 2") {
+		t.Errorf("trimmed text still contains the second page: %q", ps.Text)
+	}
+}
+
+func TestSummarizePDFRejectsMalformedDocuments(t *testing.T) {
+	if _, err := summarizePDF(&response{body: []byte("%PDF-1.4\nnot a pdf"), maxText: MaxResponseSize}); err == nil {
+		t.Fatal("a malformed document was parsed")
+	}
+}
+
+func TestFetchSniffsPDFServedAsOctetStream(t *testing.T) {
+	sample := readSamplePDF(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(sample)
+	}))
+	defer srv.Close()
+
+	// The HTML limit is smaller than the document, which must still be read
+	// in full.
+	f := newTestFetcher(t, FetcherOptions{MaxResponseSize: 256})
+	ps, err := f.Fetch(context.Background(), srv.URL+"/download?id=1", FetchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.PageCount != 2 || !strings.Contains(ps.Text, "Hello PDF") {
+		t.Errorf("got %d pages, text %q", ps.PageCount, ps.Text)
+	}
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// PolicyError is returned when a URL is rejected by the domain policy.
+type PolicyError struct {
+	URL  string `json:"url"`
+	Host string `json:"host"`
+	// Rule is the deny rule that matched, or empty when the host matched
+	// no allow rule.
+	Rule string `json:"rule,omitempty"`
+}
+
+func (e *PolicyError) Error() string {
+	if e.Rule != "" {
+		return fmt.Sprintf("policy: host %s is denied by rule %q (url: %s)", e.Host, e.Rule, e.URL)
+	}
+	return fmt.Sprintf("policy: host %s is not in the allowlist (url: %s)", e.Host, e.URL)
+}
+
+// Policy decides which hosts may be fetched and which search results are
+// shown. A nil *Policy allows everything.
+//
+// Rules are host patterns. A plain domain such as "example.com" matches the
+// domain and all of its subdomains. Patterns containing wildcards are
+// matched as globs against the whole host, so "*.example.com" matches only
+// subdomains and "docs.*" matches any "docs." host. Deny rules win over
+// allow rules; when allow rules are present, hosts matching none of them are
+// denied.
+type Policy struct {
+	allow []string
+	deny  []string
+}
+
+// NewPolicy validates and compiles allow and deny rules.
+func NewPolicy(allow, deny []string) (*Policy, error) {
+	p := &Policy{}
+	var err error
+	if p.allow, err = normalizeRules(allow); err != nil {
+		return nil, err
+	}
+	if p.deny, err = normalizeRules(deny); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func normalizeRules(rules []string) ([]string, error) {
+	out := make([]string, 0, len(rules))
+	for _, r := range rules {
+		r = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(r)), ".")
+		if r == "" {
+			continue
+		}
+		if _, err := path.Match(r, ""); err != nil {
+			return nil, fmt.Errorf("invalid domain rule %q: %w", r, err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// Check returns a *PolicyError when u's host is not permitted.
+func (p *Policy) Check(u *url.URL) error {
+	if p == nil {
+		return nil
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, r := range p.deny {
+		if matchHost(r, host) {
+			return &PolicyError{URL: u.String(), Host: host, Rule: r}
+		}
+	}
+	if len(p.allow) == 0 {
+		return nil
+	}
+	for _, r := range p.allow {
+		if matchHost(r, host) {
+			return nil
+		}
+	}
+	return &PolicyError{URL: u.String(), Host: host}
+}
+
+// Allowed reports whether rawURL passes the policy. Unparseable URLs are
+// rejected.
+func (p *Policy) Allowed(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return p.Check(u) == nil
+}
+
+func matchHost(rule, host string) bool {
+	if strings.ContainsAny(rule, "*?[") {
+		ok, _ := path.Match(rule, host)
+		return ok
+	}
+	return host == rule || strings.HasSuffix(host, "."+rule)
+}
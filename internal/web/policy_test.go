@@ -0,0 +1,115 @@
+package web
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		allow, deny []string
+		url         string
+		wantRule    string
+		denied      bool
+	}{
+		{name: "no rules", url: "https://anything.example/"},
+		{name: "suffix matches domain", allow: []string{"example.com"}, url: "https://example.com/"},
+		{name: "suffix matches subdomain", allow: []string{"example.com"}, url: "https://a.b.example.com/"},
+		{name: "suffix needs a dot boundary", allow: []string{"example.com"}, url: "https://notexample.com/", denied: true},
+		{name: "not in allowlist", allow: []string{"example.com"}, url: "https://example.org/", denied: true},
+		{name: "wildcard matches subdomain", allow: []string{"*.example.com"}, url: "https://docs.example.com/"},
+		{name: "wildcard matches deep subdomain", allow: []string{"*.example.com"}, url: "https://a.b.example.com/"},
+		{name: "wildcard skips bare domain", allow: []string{"*.example.com"}, url: "https://example.com/", denied: true},
+		{name: "trailing glob", allow: []string{"docs.*"}, url: "https://docs.example.org/"},
+		{name: "trailing glob is anchored", allow: []string{"docs.*"}, url: "https://api.docs.example.org/", denied: true},
+		{name: "single character glob", deny: []string{"mirror?.example.com"}, url: "https://mirror2.example.com/", wantRule: "mirror?.example.com", denied: true},
+		{name: "character class", deny: []string{"[ab].example.com"}, url: "https://c.example.com/"},
+		{name: "deny wins over allow", allow: []string{"example.com"}, deny: []string{"ads.example.com"}, url: "https://x.ads.example.com/", wantRule: "ads.example.com", denied: true},
+		{name: "deny only", deny: []string{"example.com"}, url: "https://example.org/"},
+		{name: "rule case", allow: []string{"  Example.COM "}, url: "https://docs.example.com/"},
+		{name: "host case", deny: []string{"example.com"}, url: "https://WWW.Example.Com/", wantRule: "example.com", denied: true},
+		{name: "trailing dot in host", deny: []string{"example.com"}, url: "https://example.com./", wantRule: "example.com", denied: true},
+		{name: "trailing dot in rule", deny: []string{"example.com."}, url: "https://example.com/", wantRule: "example.com", denied: true},
+		{name: "port is ignored", allow: []string{"example.com"}, url: "https://example.com:8443/"},
+		{name: "blank rules are skipped", allow: []string{"", " "}, url: "https://example.org/"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := NewPolicy(tc.allow, tc.deny)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := url.Parse(tc.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = p.Check(u)
+			if !tc.denied {
+				if err != nil {
+					t.Fatalf("Check = %v, want allowed", err)
+				}
+				return
+			}
+			var pe *PolicyError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Check = %v, want a PolicyError", err)
+			}
+			if pe.Rule != tc.wantRule {
+				t.Errorf("rule = %q, want %q", pe.Rule, tc.wantRule)
+			}
+		})
+	}
+}
+
+func TestNewPolicyRejectsInvalidRules(t *testing.T) {
+	if _, err := NewPolicy([]string{"[example.com"}, nil); err == nil {
+		t.Error("NewPolicy accepted an invalid allow rule")
+	}
+	if _, err := NewPolicy(nil, []string{"ex[a-.com"}); err == nil {
+		t.Error("NewPolicy accepted an invalid deny rule")
+	}
+}
+
+func TestNilPolicyAllowsEverything(t *testing.T) {
+	var p *Policy
+	if !p.Allowed("https://example.com/") {
+		t.Error("nil policy denied a URL")
+	}
+}
+
+func TestPolicyAllowed(t *testing.T) {
+	p, err := NewPolicy([]string{"example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for raw, want := range map[string]bool{
+		"https://example.com/a": true,
+		"https://example.org/a": false,
+		"http://[bad":           false,
+	} {
+		if got := p.Allowed(raw); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	for _, tc := range []struct {
+		rule, host string
+		want       bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "www.example.com", true},
+		{"example.com", "example.com.evil.net", false},
+		{"example.com", "wwwexample.com", false},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "www.example.com", true},
+		{"*", "localhost", true},
+		{"*.com", "example.org", false},
+	} {
+		if got := matchHost(tc.rule, tc.host); got != tc.want {
+			t.Errorf("matchHost(%q, %q) = %v, want %v", tc.rule, tc.host, got, tc.want)
+		}
+	}
+}
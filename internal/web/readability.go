@@ -0,0 +1,240 @@
+package web
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// The scorer below is a trimmed-down port of the Readability heuristics:
+// paragraphs earn points for their length and comma count, hand those points
+// to their ancestors, and the ancestor with the best score (after a link
+// density penalty) is taken as the article body.
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|consent|cookie|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|modal|newsletter|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental|toolbar|widget`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveWeight     = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story|documentation|docs`)
+	negativeWeight     = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|cookie|footer|gdpr|masthead|media|meta|modal|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|social|tags|tool|widget|nav|menu|breadcrumb`)
+)
+
+// boilerplateSelector matches elements that are never part of the article
+// body, regardless of their score.
+const boilerplateSelector = "nav, header, footer, aside, [role=navigation], [role=banner], [role=contentinfo], [role=complementary], [role=dialog], [role=alertdialog], [aria-modal=true], [aria-hidden=true]"
+
+// blockTags are the elements whose presence makes a div a container rather
+// than a paragraph.
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "dl": true,
+	"div": true, "fieldset": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "main": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "section": true, "table": true, "ul": true,
+}
+
+// minArticleLength is the minimum amount of text a candidate must hold to be
+// trusted as the article body.
+const minArticleLength = 140
+
+// extractArticle identifies the main content of doc and returns it as a
+// selection of sibling nodes in document order. It returns nil when no
+// convincing candidate is found, in which case callers should fall back to
+// the full document. Scoring runs on a copy of the body, so doc itself is
+// left untouched and the returned nodes are detached from it.
+//
+// Like Readability, a first pass strips elements whose class or id looks
+// unlikely to hold content; when that leaves no candidate, scoring is
+// retried with only the boilerplate removed.
+func extractArticle(doc *goquery.Document) *goquery.Selection {
+	body := doc.Find("body").First()
+	if body.Length() == 0 {
+		return nil
+	}
+	for _, strip := range []bool{true, false} {
+		work := body.Clone()
+		work.Find(boilerplateSelector).Remove()
+		if strip {
+			removeUnlikely(work)
+		}
+		if article := scoreArticle(work); article != nil {
+			return article
+		}
+	}
+	return nil
+}
+
+// scoreArticle picks the article body among the descendants of body.
+func scoreArticle(body *goquery.Selection) *goquery.Selection {
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	initialize := func(n *html.Node) {
+		if _, ok := scores[n]; ok {
+			return
+		}
+		scores[n] = tagWeight(n) + classWeight(n)
+		candidates = append(candidates, n)
+	}
+
+	body.Find("p, pre, td, blockquote, div, section").Each(func(_ int, s *goquery.Selection) {
+		n := s.Get(0)
+		if (n.Data == "div" || n.Data == "section") && hasBlockChild(n) {
+			return
+		}
+		text := normalizedText(s)
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+
+		level := 0
+		for a := n.Parent; a != nil && level < 3; a = a.Parent {
+			if a.Type != html.ElementNode || a.Data == "html" {
+				break
+			}
+			initialize(a)
+			switch level {
+			case 0:
+				scores[a] += score
+			case 1:
+				scores[a] += score / 2
+			default:
+				scores[a] += score / float64(level*3)
+			}
+			level++
+		}
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for _, n := range candidates {
+		s := scores[n] * (1 - linkDensity(goquery.NewDocumentFromNode(n).Selection))
+		scores[n] = s
+		if top == nil || s > topScore {
+			top, topScore = n, s
+		}
+	}
+	if top == nil {
+		return nil
+	}
+	if len(normalizedText(goquery.NewDocumentFromNode(top).Selection)) < minArticleLength {
+		return nil
+	}
+	if top.Data == "body" || top.Parent == nil {
+		return nil
+	}
+
+	// Gather siblings that look like they belong to the same article, such as
+	// a lead paragraph split off from the main container.
+	threshold := math.Max(10, topScore*0.2)
+	var nodes []*html.Node
+	for sib := top.Parent.FirstChild; sib != nil; sib = sib.NextSibling {
+		if sib.Type != html.ElementNode {
+			continue
+		}
+		if sib == top {
+			nodes = append(nodes, sib)
+			continue
+		}
+		if s, ok := scores[sib]; ok && s >= threshold {
+			nodes = append(nodes, sib)
+			continue
+		}
+		if sib.Data == "p" {
+			sel := goquery.NewDocumentFromNode(sib).Selection
+			text := normalizedText(sel)
+			density := linkDensity(sel)
+			if (len(text) > 80 && density < 0.25) || (len(text) > 0 && density == 0 && strings.HasSuffix(text, ".")) {
+				nodes = append(nodes, sib)
+			}
+		}
+	}
+	return body.FindNodes(nodes...)
+}
+
+// removeUnlikely strips elements whose class or id marks them as
+// navigation, comments, banners and similar page furniture.
+func removeUnlikely(body *goquery.Selection) {
+	body.Find("*").Each(func(_ int, s *goquery.Selection) {
+		n := s.Get(0)
+		if n.Data == "main" || n.Data == "article" || n.Parent == nil {
+			return
+		}
+		match := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+		if !unlikelyCandidates.MatchString(match) || maybeCandidate.MatchString(match) {
+			return
+		}
+		if s.Find("main, article").Length() > 0 {
+			return
+		}
+		s.Remove()
+	})
+}
+
+// tagWeight is the initial score of a candidate based on its element type.
+// Semantic containers are strongly favoured.
+func tagWeight(n *html.Node) float64 {
+	switch n.Data {
+	case "article", "main":
+		return 15
+	case "div", "section":
+		return 5
+	case "pre", "td", "blockquote":
+		return 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		return -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		return -5
+	}
+	for _, a := range n.Attr {
+		if (a.Key == "role" && a.Val == "main") || (a.Key == "itemprop" && a.Val == "articleBody") {
+			return 15
+		}
+	}
+	return 0
+}
+
+// classWeight rewards or penalizes a node based on its class and id.
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, a := range n.Attr {
+		if a.Key != "class" && a.Key != "id" {
+			continue
+		}
+		if negativeWeight.MatchString(a.Val) {
+			weight -= 25
+		}
+		if positiveWeight.MatchString(a.Val) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of s's text that sits inside links.
+func linkDensity(s *goquery.Selection) float64 {
+	total := len(normalizedText(s))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linked += len(normalizedText(a))
+	})
+	return float64(linked) / float64(total)
+}
+
+func hasBlockChild(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && blockTags[c.Data] {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizedText(s *goquery.Selection) string {
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
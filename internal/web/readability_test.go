@@ -0,0 +1,56 @@
+package web
+
+import (
+	"strings"
+	"testing"
+)
+
+func summarizeHTML(t *testing.T, page string, opts FetchOptions) *PageSummary {
+	t.Helper()
+	ps, err := summarize(&response{
+		finalURL:    "https://example.com/docs/page",
+		contentType: "text/html; charset=utf-8",
+		body:        []byte(page),
+	}, opts)
+	if err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+	return ps
+}
+
+func TestArticleModeFallsBackToFullPage(t *testing.T) {
+	page := `<html><body><div class="layout has-sidebar"><h1>Guide</h1><p>Short intro.</p><ul><li>One</li><li>Two</li></ul></div></body></html>`
+	full := summarizeHTML(t, page, FetchOptions{Mode: ModeFull})
+	article := summarizeHTML(t, page, FetchOptions{Mode: ModeArticle})
+	if article.Text == "" || article.Text != full.Text {
+		t.Fatalf("article mode text = %q, want the full page %q", article.Text, full.Text)
+	}
+}
+
+func TestArticleModeKeepsMainContent(t *testing.T) {
+	body := strings.Repeat("This paragraph explains the feature in detail, with commas, and more words. ", 4)
+	page := `<html><body><nav><a href="/">Home</a></nav>` +
+		`<div class="sidebar"><p>Subscribe to our newsletter for updates and offers.</p></div>` +
+		`<article><h1>Feature</h1><p>` + body + `</p><p>` + body + `</p></article>` +
+		`<footer>Copyright</footer></body></html>`
+	ps := summarizeHTML(t, page, FetchOptions{Mode: ModeArticle})
+	if !strings.Contains(ps.Text, "explains the feature") {
+		t.Errorf("article text is missing the body: %q", ps.Text)
+	}
+	for _, unwanted := range []string{"newsletter", "Copyright", "Home"} {
+		if strings.Contains(ps.Text, unwanted) {
+			t.Errorf("article text contains %q: %q", unwanted, ps.Text)
+		}
+	}
+}
+
+func TestParseModeDefaultsToFullPage(t *testing.T) {
+	for in, want := range map[string]Mode{"": ModeFull, "full": ModeFull, "article": ModeArticle} {
+		if got, err := ParseMode(in); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseMode("reader"); err == nil {
+		t.Error("ParseMode accepted an unknown mode")
+	}
+}
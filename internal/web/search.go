@@ -4,141 +4,294 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"html"
 	"net/http"
-	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/leonardcser/web-mcp/internal/cache"
 )
 
-// extractDDGURL extracts the actual URL from DuckDuckGo's redirect URL format
-// Input: //duckduckgo.com/l/?uddg=https%3A%2F%2Fexample.com&rut=...
-// Output: https://example.com
-func extractDDGURL(ddgURL string) string {
-	// Handle protocol-relative URLs
-	if strings.HasPrefix(ddgURL, "//duckduckgo.com/l/") {
-		ddgURL = "https:" + ddgURL
-	}
+const (
+	// MaxSearchResults is the page size requested from backends, and the
+	// largest number of results returned by a single search.
+	MaxSearchResults = 20
+	// MaxSearchPage is the deepest result page that can be requested.
+	MaxSearchPage = 10
+)
 
-	u, err := url.Parse(ddgURL)
-	if err != nil {
-		return ddgURL // Return original if parsing fails
+type SearchResult struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Link        string `json:"link"`
+}
+
+// TimeRange restricts results to pages published recently.
+type TimeRange string
+
+const (
+	TimeAny   TimeRange = ""
+	TimeDay   TimeRange = "day"
+	TimeWeek  TimeRange = "week"
+	TimeMonth TimeRange = "month"
+	TimeYear  TimeRange = "year"
+)
+
+// SafeSearch controls filtering of adult content.
+type SafeSearch string
+
+const (
+	SafeDefault  SafeSearch = ""
+	SafeOff      SafeSearch = "off"
+	SafeModerate SafeSearch = "moderate"
+	SafeStrict   SafeSearch = "strict"
+)
+
+// SearchRequest describes a single search.
+type SearchRequest struct {
+	Query string
+	// Limit is the page size asked of providers that take one. Backends
+	// return the provider's whole page, which may hold more or fewer
+	// results, so that paging never skips any.
+	Limit int
+	// Region is a "country-language" pair such as "us-en" or "de-de", or
+	// "wt-wt" for no region. Empty uses the backend default.
+	Region string
+	// Language is a language code such as "en" or "fr". When empty it is
+	// derived from Region by backends that support it.
+	Language   string
+	TimeRange  TimeRange
+	SafeSearch SafeSearch
+	// Page is the 1-based result page. Searcher pages by Limit; backends
+	// are always asked for pages of MaxSearchResults.
+	Page int
+}
+
+// normalize cleans up the optional fields of r and reports invalid values.
+func (r *SearchRequest) normalize() error {
+	r.Region = strings.ToLower(strings.TrimSpace(r.Region))
+	r.Language = strings.ToLower(strings.TrimSpace(r.Language))
+	if r.Page == 0 {
+		r.Page = 1
+	}
+	if r.Page < 1 || r.Page > MaxSearchPage {
+		return fmt.Errorf("invalid page %d: must be between 1 and %d", r.Page, MaxSearchPage)
 	}
+	if r.Region != "" {
+		if country, lang := splitRegion(r.Region); country == "" || lang == "" {
+			return fmt.Errorf("invalid region %q: expected a country-language pair such as \"us-en\"", r.Region)
+		}
+	}
+	switch r.TimeRange {
+	case TimeAny, TimeDay, TimeWeek, TimeMonth, TimeYear:
+	default:
+		return fmt.Errorf("invalid time_range %q: must be day, week, month or year", r.TimeRange)
+	}
+	switch r.SafeSearch {
+	case SafeDefault, SafeOff, SafeModerate, SafeStrict:
+	default:
+		return fmt.Errorf("invalid safe_search %q: must be off, moderate or strict", r.SafeSearch)
+	}
+	return nil
+}
 
-	// Extract the uddg parameter which contains the actual URL
-	uddg := u.Query().Get("uddg")
-	if uddg == "" {
-		return ddgURL // Return original if no uddg parameter
+// splitRegion splits a "country-language" region into its parts.
+func splitRegion(region string) (country, lang string) {
+	country, lang, _ = strings.Cut(region, "-")
+	return country, lang
+}
+
+// regionLanguage returns the explicit language of r, or the language part
+// of its region. It is "" when neither names a specific language.
+func (r SearchRequest) regionLanguage() string {
+	if r.Language != "" {
+		return r.Language
 	}
+	_, lang := splitRegion(r.Region)
+	if lang == "wt" {
+		return ""
+	}
+	return lang
+}
 
-	// URL decode the actual URL
-	actualURL, err := url.QueryUnescape(uddg)
-	if err != nil {
-		return ddgURL // Return original if decoding fails
+// regionCountry returns the country part of r's region, or "" when no
+// specific region was requested.
+func (r SearchRequest) regionCountry() string {
+	country, _ := splitRegion(r.Region)
+	if country == "wt" {
+		return ""
 	}
+	return country
+}
 
-	return actualURL
+// SearchBackend performs searches against a single provider.
+// Implementations must be safe for concurrent use by multiple goroutines.
+type SearchBackend interface {
+	// Name identifies the backend in cache keys and logs.
+	Name() string
+	Search(ctx context.Context, req SearchRequest) ([]SearchResult, error)
 }
 
-type SearchResult struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Link        string `json:"link"`
+// BackendConfig selects and configures a search backend.
+type BackendConfig struct {
+	// Name is one of "duckduckgo" (default), "searxng", "brave" or "bing".
+	Name string
+	// Endpoint overrides the provider's default URL. It is required for
+	// SearXNG, where it is the base URL of the instance.
+	Endpoint string
+	// APIKey authenticates against the Brave and Bing APIs.
+	APIKey string
+}
+
+// NewSearchBackend builds the backend described by cfg.
+func NewSearchBackend(cfg BackendConfig) (SearchBackend, error) {
+	switch strings.ToLower(cfg.Name) {
+	case "", "duckduckgo", "ddg":
+		return NewDuckDuckGoBackend(cfg.Endpoint), nil
+	case "searxng":
+		if cfg.Endpoint == "" {
+			return nil, fmt.Errorf("searxng backend requires an endpoint")
+		}
+		return NewSearXNGBackend(cfg.Endpoint), nil
+	case "brave":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("brave backend requires an API key")
+		}
+		return NewBraveBackend(cfg.Endpoint, cfg.APIKey), nil
+	case "bing":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("bing backend requires an API key")
+		}
+		return NewBingBackend(cfg.Endpoint, cfg.APIKey), nil
+	}
+	return nil, fmt.Errorf("unknown search backend %q", cfg.Name)
+}
+
+// SearcherOptions configures a Searcher.
+type SearcherOptions struct {
+	// TTL is how long result pages are cached.
+	TTL time.Duration
+	// Policy hides results whose links point at denied hosts. Nil shows
+	// every result.
+	Policy *Policy
 }
 
+// Searcher runs searches through a backend and caches the results.
 type Searcher struct {
-	client *http.Client
-	cache  cache.KV
-	ttl    time.Duration
+	backend SearchBackend
+	cache   cache.KV
+	opts    SearcherOptions
 }
 
-func NewSearcher(cacheStore cache.KV, ttl time.Duration) *Searcher {
+func NewSearcher(backend SearchBackend, cacheStore cache.KV, opts SearcherOptions) *Searcher {
 	return &Searcher{
-		client: &http.Client{Timeout: 15 * time.Second},
-		cache:  cacheStore,
-		ttl:    ttl,
+		backend: backend,
+		cache:   cacheStore,
+		opts:    opts,
 	}
 }
 
-func (s *Searcher) cacheKey(q string) string { return "web_search|" + q }
+func (s *Searcher) cacheKey(req SearchRequest) string {
+	return strings.Join([]string{
+		"web_search", s.backend.Name(), req.Region, req.Language,
+		string(req.TimeRange), string(req.SafeSearch), strconv.Itoa(req.Page), req.Query,
+	}, "|")
+}
 
-func (s *Searcher) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
-	q := strings.TrimSpace(query)
-	if q == "" {
+// Search returns page req.Page of the results, req.Limit per page. Limit
+// defaults to 10 and is capped at 20. Results are read from consecutive
+// backend pages, each cached independently and kept whole whatever their
+// size, so paging with any limit covers every result exactly once.
+func (s *Searcher) Search(ctx context.Context, req SearchRequest) ([]SearchResult, error) {
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
 		return nil, fmt.Errorf("empty query")
 	}
-	if limit <= 0 || limit > 20 {
+	if err := req.normalize(); err != nil {
+		return nil, err
+	}
+	limit := req.Limit
+	if limit <= 0 || limit > MaxSearchResults {
 		limit = 10
 	}
-	if v, err := s.cache.Get(s.cacheKey(q)); err == nil {
+	start := (req.Page - 1) * limit
+	end := start + limit
+	// Always fetch the maximum so the cached entries serve any limit.
+	req.Limit = MaxSearchResults
+	var results []SearchResult
+	for page := 1; page <= MaxSearchPage && len(results) < end; page++ {
+		req.Page = page
+		batch, err := s.backendPage(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		results = append(results, s.filter(batch)...)
+	}
+	return results[min(start, len(results)):min(end, len(results))], nil
+}
+
+// backendPage returns one page of backend results, from the cache when
+// possible.
+func (s *Searcher) backendPage(ctx context.Context, req SearchRequest) ([]SearchResult, error) {
+	if v, err := s.cache.Get(s.cacheKey(req)); err == nil {
 		var cached []SearchResult
 		if json.Unmarshal(v, &cached) == nil {
-			if len(cached) > limit {
-				return cached[:limit], nil
-			}
 			return cached, nil
 		}
 	}
-	endpoint := "https://html.duckduckgo.com/html/"
-	values := url.Values{"q": {q}, "kl": {"us-en"}}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+values.Encode(), nil)
+	results, err := s.backend.Search(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", NextUserAgent())
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	if b, err := json.Marshal(results); err == nil {
+		_ = s.cache.Put(s.cacheKey(req), b, s.opts.TTL)
+	}
+	return results, nil
+}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, err
+// filter drops results denied by the policy. The unfiltered pages are
+// what gets cached, so policy changes apply immediately.
+func (s *Searcher) filter(results []SearchResult) []SearchResult {
+	if s.opts.Policy == nil {
+		return results
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("duckduckgo status %d", resp.StatusCode)
+	out := make([]SearchResult, 0, len(results))
+	for _, r := range results {
+		if s.opts.Policy.Allowed(r.Link) {
+			out = append(out, r)
+		}
 	}
+	return out
+}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+// getJSON issues a GET request to endpoint and decodes the JSON response
+// into out. name is used in error messages.
+func getJSON(ctx context.Context, client *http.Client, name, endpoint string, header http.Header, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	results := make([]SearchResult, 0, limit)
-	// Use concrete selectors from the DuckDuckGo HTML endpoint structure.
-	doc.Find("div.result.results_links.results_links_deep.web-result").EachWithBreak(func(_ int, s *goquery.Selection) bool {
-		a := s.Find("a.result__a").First()
-		link := strings.TrimSpace(a.AttrOr("href", ""))
-		title := singleLine(a.Text())
-		desc := singleLine(s.Find("a.result__snippet").First().Text())
-		if title != "" && link != "" {
-			// Extract the actual URL from DuckDuckGo's redirect URL
-			actualLink := extractDDGURL(link)
-			results = append(results, SearchResult{Title: title, Description: desc, Link: actualLink})
-		}
-		return len(results) < limit
-	})
-
-	if len(results) == 0 {
-		// Fallback: scan anchor list and nearest snippet up the tree
-		doc.Find("a.result__a").EachWithBreak(func(_ int, n *goquery.Selection) bool {
-			if len(results) >= limit {
-				return false
-			}
-			title := singleLine(n.Text())
-			link := strings.TrimSpace(n.AttrOr("href", ""))
-			desc := singleLine(n.Parents().Find("a.result__snippet").First().Text())
-			// Extract the actual URL from DuckDuckGo's redirect URL
-			actualLink := extractDDGURL(link)
-			results = append(results, SearchResult{Title: title, Description: desc, Link: actualLink})
-			return true
-		})
+	for k, v := range header {
+		req.Header[k] = v
 	}
-	if b, err := json.Marshal(results); err == nil {
-		_ = s.cache.Put(s.cacheKey(q), b, s.ttl)
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
 	}
-	return results, nil
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s status %d", name, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s: invalid response: %w", name, err)
+	}
+	return nil
 }
 
 // singleLine trims and collapses internal whitespace/newlines to single spaces.
@@ -149,3 +302,10 @@ func singleLine(s string) string {
 	}
 	return strings.Join(strings.Fields(s), " ")
 }
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// plainSnippet strips highlighting markup and entities from API snippets.
+func plainSnippet(s string) string {
+	return singleLine(html.UnescapeString(htmlTag.ReplaceAllString(s, "")))
+}
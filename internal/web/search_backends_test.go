@@ -0,0 +1,256 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// standIn is a local search provider that records the requests it gets and
+// answers each with the next canned body.
+type standIn struct {
+	t           *testing.T
+	contentType string
+	bodies      []string
+
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+type recordedRequest struct {
+	method string
+	query  url.Values
+	form   url.Values
+	header http.Header
+}
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	form, _ := url.ParseQuery(string(body))
+	s.mu.Lock()
+	n := len(s.requests)
+	s.requests = append(s.requests, recordedRequest{method: r.Method, query: r.URL.Query(), form: form, header: r.Header.Clone()})
+	s.mu.Unlock()
+	if n >= len(s.bodies) {
+		s.t.Errorf("unexpected request %d: %s %s", n+1, r.Method, r.URL)
+		http.Error(w, "no more pages", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", s.contentType)
+	io.WriteString(w, s.bodies[n])
+}
+
+// assertParams checks that every parameter in want has exactly the given
+// value in got, and that the parameters mapped to "" are absent.
+func assertParams(t *testing.T, got url.Values, want map[string]string) {
+	t.Helper()
+	for k, v := range want {
+		if v == "" {
+			if got.Has(k) {
+				t.Errorf("parameter %s = %q, want it absent", k, got.Get(k))
+			}
+			continue
+		}
+		if got.Get(k) != v {
+			t.Errorf("parameter %s = %q, want %q", k, got.Get(k), v)
+		}
+	}
+}
+
+func TestAPIBackends(t *testing.T) {
+	tests := []struct {
+		name       string
+		newBackend func(endpoint string) SearchBackend
+		req        SearchRequest
+		response   string
+		wantParams map[string]string
+		wantHeader map[string]string
+		want       []SearchResult
+	}{
+		{
+			name:       "searxng",
+			newBackend: func(endpoint string) SearchBackend { return NewSearXNGBackend(endpoint) },
+			req:        SearchRequest{Query: "go generics", Limit: 2, Region: "de-de", TimeRange: TimeWeek, SafeSearch: SafeStrict, Page: 3},
+			response: `{"results": [
+				{"title": "Generics  in Go", "url": "https://go.dev/doc/tutorial/generics", "content": "A <b>tutorial</b> &amp; more"},
+				{"title": "No URL", "url": "", "content": "skipped"},
+				{"title": "Proposal", "url": "https://go.dev/design/43651", "content": "Type parameters"},
+				{"title": "Past the limit", "url": "https://go.dev/blog/intro-generics", "content": "Kept"}
+			]}`,
+			wantParams: map[string]string{"q": "go generics", "format": "json", "pageno": "3", "language": "de-DE", "time_range": "week", "safesearch": "2"},
+			want: []SearchResult{
+				{Title: "Generics in Go", Description: "A tutorial & more", Link: "https://go.dev/doc/tutorial/generics"},
+				{Title: "Proposal", Description: "Type parameters", Link: "https://go.dev/design/43651"},
+				{Title: "Past the limit", Description: "Kept", Link: "https://go.dev/blog/intro-generics"},
+			},
+		},
+		{
+			name:       "searxng explicit language",
+			newBackend: func(endpoint string) SearchBackend { return NewSearXNGBackend(endpoint + "/search/") },
+			req:        SearchRequest{Query: "q", Limit: 5, Region: "us-en", Language: "fr", Page: 1},
+			response:   `{"results": []}`,
+			wantParams: map[string]string{"language": "fr", "time_range": "", "safesearch": ""},
+			want:       []SearchResult{},
+		},
+		{
+			name:       "searxng worldwide",
+			newBackend: func(endpoint string) SearchBackend { return NewSearXNGBackend(endpoint) },
+			req:        SearchRequest{Query: "q", Limit: 10, Region: "wt-wt", Page: 1},
+			response:   `{"results": []}`,
+			wantParams: map[string]string{"pageno": "1", "language": ""},
+			want:       []SearchResult{},
+		},
+		{
+			name:       "brave",
+			newBackend: func(endpoint string) SearchBackend { return NewBraveBackend(endpoint, "brave-key") },
+			req:        SearchRequest{Query: "rust async", Limit: 20, Region: "gb-en", TimeRange: TimeMonth, SafeSearch: SafeOff, Page: 2},
+			response: `{"web": {"results": [
+				{"title": "<strong>Async</strong> Rust", "url": "https://rust-lang.github.io/async-book/", "description": "The async book"}
+			]}}`,
+			wantParams: map[string]string{"q": "rust async", "count": "20", "offset": "1", "country": "gb", "search_lang": "en", "freshness": "pm", "safesearch": "off"},
+			wantHeader: map[string]string{"X-Subscription-Token": "brave-key", "Accept": "application/json"},
+			want: []SearchResult{
+				{Title: "Async Rust", Description: "The async book", Link: "https://rust-lang.github.io/async-book/"},
+			},
+		},
+		{
+			name:       "brave worldwide",
+			newBackend: func(endpoint string) SearchBackend { return NewBraveBackend(endpoint, "k") },
+			req:        SearchRequest{Query: "q", Limit: 10, Region: "wt-wt", Page: 1},
+			response:   `{"web": {"results": []}}`,
+			wantParams: map[string]string{"offset": "0", "country": "", "search_lang": "", "freshness": "", "safesearch": ""},
+			want:       []SearchResult{},
+		},
+		{
+			name:       "bing",
+			newBackend: func(endpoint string) SearchBackend { return NewBingBackend(endpoint, "bing-key") },
+			req:        SearchRequest{Query: "kubernetes", Limit: 20, Region: "fr-fr", Language: "en", TimeRange: TimeDay, SafeSearch: SafeModerate, Page: 3},
+			response: `{"webPages": {"value": [
+				{"name": "Kubernetes\n Docs", "url": "https://kubernetes.io/docs/", "snippet": "Production-Grade &lt;Container&gt; Orchestration"}
+			]}}`,
+			wantParams: map[string]string{"q": "kubernetes", "count": "20", "offset": "40", "mkt": "fr-FR", "setLang": "en", "freshness": "Day", "safeSearch": "Moderate", "responseFilter": "Webpages"},
+			wantHeader: map[string]string{"Ocp-Apim-Subscription-Key": "bing-key"},
+			want: []SearchResult{
+				{Title: "Kubernetes Docs", Description: "Production-Grade <Container> Orchestration", Link: "https://kubernetes.io/docs/"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &standIn{t: t, contentType: "application/json", bodies: []string{tt.response}}
+			srv := httptest.NewServer(provider)
+			defer srv.Close()
+
+			got, err := tt.newBackend(srv.URL).Search(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("results = %+v, want %+v", got, tt.want)
+			}
+			if len(provider.requests) != 1 {
+				t.Fatalf("got %d requests, want 1", len(provider.requests))
+			}
+			sent := provider.requests[0]
+			assertParams(t, sent.query, tt.wantParams)
+			for k, v := range tt.wantHeader {
+				if sent.header.Get(k) != v {
+					t.Errorf("header %s = %q, want %q", k, sent.header.Get(k), v)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIBackendStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := NewBraveBackend(srv.URL, "k").Search(context.Background(), SearchRequest{Query: "q", Limit: 10, Page: 1})
+	if err == nil || err.Error() != "brave status 429" {
+		t.Fatalf("err = %v, want brave status 429", err)
+	}
+}
+
+const ddgPage1 = `<html><body>
+<div class="result results_links results_links_deep web-result">
+  <a class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2F&amp;rut=abc">The Go  Documentation</a>
+  <a class="result__snippet" href="#">Docs for <b>Go</b>.</a>
+</div>
+<div class="result results_links results_links_deep web-result">
+  <a class="result__a" href="https://pkg.go.dev/">Go Packages</a>
+  <a class="result__snippet" href="#">Package index</a>
+</div>
+<form action="/html/" method="post">
+  <input type="submit" value="Next">
+  <input type="hidden" name="q" value="golang">
+  <input type="hidden" name="s" value="10">
+  <input type="hidden" name="dc" value="11">
+</form>
+</body></html>`
+
+const ddgPage2 = `<html><body>
+<div class="result results_links results_links_deep web-result">
+  <a class="result__a" href="https://go.dev/blog/">The Go Blog</a>
+  <a class="result__snippet" href="#">News</a>
+</div>
+</body></html>`
+
+func TestDuckDuckGoBackend(t *testing.T) {
+	provider := &standIn{t: t, contentType: "text/html", bodies: []string{ddgPage1, ddgPage2, ddgPage2}}
+	srv := httptest.NewServer(provider)
+	defer srv.Close()
+	b := NewDuckDuckGoBackend(srv.URL)
+
+	req := SearchRequest{Query: "golang", Limit: 10, TimeRange: TimeYear, SafeSearch: SafeOff, Page: 1}
+	got, err := b.Search(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SearchResult{
+		{Title: "The Go Documentation", Description: "Docs for Go.", Link: "https://go.dev/doc/"},
+		{Title: "Go Packages", Description: "Package index", Link: "https://pkg.go.dev/"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("page 1 = %+v, want %+v", got, want)
+	}
+	first := provider.requests[0]
+	if first.method != http.MethodGet {
+		t.Errorf("page 1 method = %s, want GET", first.method)
+	}
+	assertParams(t, first.query, map[string]string{"q": "golang", "kl": "us-en", "df": "y", "kp": "-2"})
+
+	// Page 2 posts the remembered "Next" form.
+	req.Page = 2
+	got, err = b.Search(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Link != "https://go.dev/blog/" {
+		t.Errorf("page 2 = %+v", got)
+	}
+	second := provider.requests[1]
+	if second.method != http.MethodPost {
+		t.Errorf("page 2 method = %s, want POST", second.method)
+	}
+	assertParams(t, second.form, map[string]string{"q": "golang", "s": "10", "dc": "11"})
+
+	// Page 3 does not exist: the last page has no "Next" form.
+	req.Page = 3
+	b2 := NewDuckDuckGoBackend(srv.URL)
+	b2.setCursor(req, 2, second.form)
+	got, err = b2.Search(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("page 3 = %+v, want no results", got)
+	}
+}
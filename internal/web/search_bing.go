@@ -0,0 +1,81 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultBingEndpoint = "https://api.bing.microsoft.com/v7.0/search"
+
+// BingBackend queries the Bing Web Search API.
+type BingBackend struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+}
+
+func NewBingBackend(endpoint, apiKey string) *BingBackend {
+	if endpoint == "" {
+		endpoint = defaultBingEndpoint
+	}
+	return &BingBackend{
+		client:   &http.Client{Timeout: 15 * time.Second},
+		endpoint: endpoint,
+		apiKey:   apiKey,
+	}
+}
+
+func (b *BingBackend) Name() string { return "bing" }
+
+func (b *BingBackend) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	values := url.Values{
+		"q":               {sr.Query},
+		"count":           {strconv.Itoa(sr.Limit)},
+		"offset":          {strconv.Itoa((sr.Page - 1) * sr.Limit)},
+		"responseFilter":  {"Webpages"},
+		"textDecorations": {"false"},
+	}
+	if country := sr.regionCountry(); country != "" {
+		_, lang := splitRegion(sr.Region)
+		values.Set("mkt", lang+"-"+strings.ToUpper(country))
+	}
+	if sr.Language != "" {
+		values.Set("setLang", sr.Language)
+	}
+	switch sr.TimeRange {
+	case TimeDay, TimeWeek, TimeMonth:
+		values.Set("freshness", strings.ToUpper(string(sr.TimeRange)[:1])+string(sr.TimeRange)[1:])
+	case TimeYear:
+		// Bing has no "year" bucket; use an explicit date range instead.
+		now := time.Now().UTC()
+		values.Set("freshness", now.AddDate(-1, 0, 0).Format("2006-01-02")+".."+now.Format("2006-01-02"))
+	}
+	if sr.SafeSearch != SafeDefault {
+		values.Set("safeSearch", strings.ToUpper(string(sr.SafeSearch)[:1])+string(sr.SafeSearch)[1:])
+	}
+	header := http.Header{"Ocp-Apim-Subscription-Key": {b.apiKey}}
+	var body struct {
+		WebPages struct {
+			Value []struct {
+				Name    string `json:"name"`
+				URL     string `json:"url"`
+				Snippet string `json:"snippet"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	if err := getJSON(ctx, b.client, "bing", b.endpoint+"?"+values.Encode(), header, &body); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(body.WebPages.Value))
+	for _, r := range body.WebPages.Value {
+		if r.URL == "" {
+			continue
+		}
+		results = append(results, SearchResult{Title: singleLine(r.Name), Description: plainSnippet(r.Snippet), Link: r.URL})
+	}
+	return results, nil
+}
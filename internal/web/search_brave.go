@@ -0,0 +1,73 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const defaultBraveEndpoint = "https://api.search.brave.com/res/v1/web/search"
+
+// BraveBackend queries the Brave Search web API.
+type BraveBackend struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+}
+
+func NewBraveBackend(endpoint, apiKey string) *BraveBackend {
+	if endpoint == "" {
+		endpoint = defaultBraveEndpoint
+	}
+	return &BraveBackend{
+		client:   &http.Client{Timeout: 15 * time.Second},
+		endpoint: endpoint,
+		apiKey:   apiKey,
+	}
+}
+
+func (b *BraveBackend) Name() string { return "brave" }
+
+func (b *BraveBackend) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	// Brave's offset counts pages of size count, not results.
+	values := url.Values{
+		"q":      {sr.Query},
+		"count":  {strconv.Itoa(sr.Limit)},
+		"offset": {strconv.Itoa(sr.Page - 1)},
+	}
+	if country := sr.regionCountry(); country != "" {
+		values.Set("country", country)
+	}
+	if lang := sr.regionLanguage(); lang != "" {
+		values.Set("search_lang", lang)
+	}
+	if sr.TimeRange != TimeAny {
+		values.Set("freshness", "p"+string(sr.TimeRange)[:1])
+	}
+	if sr.SafeSearch != SafeDefault {
+		values.Set("safesearch", string(sr.SafeSearch))
+	}
+	header := http.Header{"X-Subscription-Token": {b.apiKey}}
+	var body struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := getJSON(ctx, b.client, "brave", b.endpoint+"?"+values.Encode(), header, &body); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(body.Web.Results))
+	for _, r := range body.Web.Results {
+		if r.URL == "" {
+			continue
+		}
+		results = append(results, SearchResult{Title: plainSnippet(r.Title), Description: plainSnippet(r.Description), Link: r.URL})
+	}
+	return results, nil
+}
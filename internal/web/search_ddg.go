@@ -0,0 +1,224 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const defaultDDGEndpoint = "https://html.duckduckgo.com/html/"
+
+// maxDDGCursors bounds the number of remembered "next page" forms.
+const maxDDGCursors = 1024
+
+// DuckDuckGoBackend scrapes the DuckDuckGo HTML endpoint. It needs no API
+// key but depends on DuckDuckGo's markup.
+//
+// DuckDuckGo has no page parameter: later pages are reached by submitting
+// the "Next" form of the previous page. The backend remembers those forms so
+// walking through pages in order costs a single request per page.
+type DuckDuckGoBackend struct {
+	client   *http.Client
+	endpoint string
+
+	mu      sync.Mutex
+	cursors map[string]url.Values
+}
+
+func NewDuckDuckGoBackend(endpoint string) *DuckDuckGoBackend {
+	if endpoint == "" {
+		endpoint = defaultDDGEndpoint
+	}
+	return &DuckDuckGoBackend{
+		client:   &http.Client{Timeout: 15 * time.Second},
+		endpoint: endpoint,
+		cursors:  make(map[string]url.Values),
+	}
+}
+
+func (b *DuckDuckGoBackend) Name() string { return "duckduckgo" }
+
+func (b *DuckDuckGoBackend) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	region := sr.Region
+	if region == "" {
+		region = "us-en"
+	}
+	values := url.Values{"q": {sr.Query}, "kl": {region}}
+	if sr.TimeRange != TimeAny {
+		values.Set("df", string(sr.TimeRange)[:1])
+	}
+	switch sr.SafeSearch {
+	case SafeStrict:
+		values.Set("kp", "1")
+	case SafeModerate:
+		values.Set("kp", "-1")
+	case SafeOff:
+		values.Set("kp", "-2")
+	}
+
+	// Start from the deepest page whose form is already known.
+	page, form := 1, url.Values(nil)
+	for p := sr.Page; p > 1; p-- {
+		if f := b.cursor(sr, p); f != nil {
+			page, form = p, f
+			break
+		}
+	}
+	for {
+		var doc *goquery.Document
+		var err error
+		if form == nil {
+			doc, err = b.fetch(ctx, http.MethodGet, values)
+		} else {
+			doc, err = b.fetch(ctx, http.MethodPost, form)
+		}
+		if err != nil {
+			return nil, err
+		}
+		next := nextPageForm(doc)
+		if next != nil {
+			b.setCursor(sr, page+1, next)
+		}
+		if page == sr.Page {
+			return parseDDGResults(doc), nil
+		}
+		if next == nil {
+			// Ran out of pages before reaching the requested one.
+			return []SearchResult{}, nil
+		}
+		page, form = page+1, next
+	}
+}
+
+// fetch loads one results page, either with a GET of the initial query or a
+// POST of a "Next" form.
+func (b *DuckDuckGoBackend) fetch(ctx context.Context, method string, values url.Values) (*goquery.Document, error) {
+	var req *http.Request
+	var err error
+	if method == http.MethodGet {
+		req, err = http.NewRequestWithContext(ctx, method, b.endpoint+"?"+values.Encode(), nil)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, method, b.endpoint, strings.NewReader(values.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", NextUserAgent())
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("duckduckgo status %d", resp.StatusCode)
+	}
+	return goquery.NewDocumentFromReader(resp.Body)
+}
+
+func (b *DuckDuckGoBackend) cursorKey(sr SearchRequest, page int) string {
+	return strings.Join([]string{sr.Region, string(sr.TimeRange), string(sr.SafeSearch), strconv.Itoa(page), sr.Query}, "|")
+}
+
+func (b *DuckDuckGoBackend) cursor(sr SearchRequest, page int) url.Values {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.cursors[b.cursorKey(sr, page)]
+}
+
+func (b *DuckDuckGoBackend) setCursor(sr SearchRequest, page int, form url.Values) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.cursors) >= maxDDGCursors {
+		clear(b.cursors)
+	}
+	b.cursors[b.cursorKey(sr, page)] = form
+}
+
+// nextPageForm returns the fields of the "Next" form on a results page, or
+// nil on the last page.
+func nextPageForm(doc *goquery.Document) url.Values {
+	var form url.Values
+	doc.Find("form").EachWithBreak(func(_ int, f *goquery.Selection) bool {
+		if f.Find(`input[type=submit][value="Next"]`).Length() == 0 {
+			return true
+		}
+		form = url.Values{}
+		f.Find("input[name]").Each(func(_ int, in *goquery.Selection) {
+			form.Add(in.AttrOr("name", ""), in.AttrOr("value", ""))
+		})
+		return false
+	})
+	return form
+}
+
+// parseDDGResults extracts the results of a results page.
+func parseDDGResults(doc *goquery.Document) []SearchResult {
+	results := []SearchResult{}
+	// Use concrete selectors from the DuckDuckGo HTML endpoint structure.
+	doc.Find("div.result.results_links.results_links_deep.web-result").Each(func(_ int, s *goquery.Selection) {
+		a := s.Find("a.result__a").First()
+		link := strings.TrimSpace(a.AttrOr("href", ""))
+		title := singleLine(a.Text())
+		desc := singleLine(s.Find("a.result__snippet").First().Text())
+		if title != "" && link != "" {
+			// Extract the actual URL from DuckDuckGo's redirect URL
+			actualLink := extractDDGURL(link)
+			results = append(results, SearchResult{Title: title, Description: desc, Link: actualLink})
+		}
+	})
+
+	if len(results) == 0 {
+		// Fallback: scan anchor list and nearest snippet up the tree
+		doc.Find("a.result__a").Each(func(_ int, n *goquery.Selection) {
+			title := singleLine(n.Text())
+			link := strings.TrimSpace(n.AttrOr("href", ""))
+			desc := singleLine(n.Parents().Find("a.result__snippet").First().Text())
+			// Extract the actual URL from DuckDuckGo's redirect URL
+			actualLink := extractDDGURL(link)
+			results = append(results, SearchResult{Title: title, Description: desc, Link: actualLink})
+		})
+	}
+	return results
+}
+
+// extractDDGURL extracts the actual URL from DuckDuckGo's redirect URL format
+// Input: //duckduckgo.com/l/?uddg=https%3A%2F%2Fexample.com&rut=...
+// Output: https://example.com
+func extractDDGURL(ddgURL string) string {
+	// Handle protocol-relative URLs
+	if strings.HasPrefix(ddgURL, "//duckduckgo.com/l/") {
+		ddgURL = "https:" + ddgURL
+	}
+
+	u, err := url.Parse(ddgURL)
+	if err != nil {
+		return ddgURL // Return original if parsing fails
+	}
+
+	// Extract the uddg parameter which contains the actual URL
+	uddg := u.Query().Get("uddg")
+	if uddg == "" {
+		return ddgURL // Return original if no uddg parameter
+	}
+
+	// URL decode the actual URL
+	actualURL, err := url.QueryUnescape(uddg)
+	if err != nil {
+		return ddgURL // Return original if decoding fails
+	}
+
+	return actualURL
+}
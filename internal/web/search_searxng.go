@@ -0,0 +1,67 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SearXNGBackend queries the JSON API of a SearXNG instance. The instance
+// must have the "json" output format enabled.
+type SearXNGBackend struct {
+	client   *http.Client
+	endpoint string
+}
+
+// NewSearXNGBackend returns a backend for the instance at baseURL, for
+// example "https://searx.example.org".
+func NewSearXNGBackend(baseURL string) *SearXNGBackend {
+	return &SearXNGBackend{
+		client:   &http.Client{Timeout: 15 * time.Second},
+		endpoint: strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/search") + "/search",
+	}
+}
+
+func (b *SearXNGBackend) Name() string { return "searxng" }
+
+func (b *SearXNGBackend) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	values := url.Values{"q": {sr.Query}, "format": {"json"}, "pageno": {strconv.Itoa(sr.Page)}}
+	if lang := sr.regionLanguage(); lang != "" {
+		if country := sr.regionCountry(); country != "" && sr.Language == "" {
+			lang += "-" + strings.ToUpper(country)
+		}
+		values.Set("language", lang)
+	}
+	if sr.TimeRange != TimeAny {
+		values.Set("time_range", string(sr.TimeRange))
+	}
+	switch sr.SafeSearch {
+	case SafeOff:
+		values.Set("safesearch", "0")
+	case SafeModerate:
+		values.Set("safesearch", "1")
+	case SafeStrict:
+		values.Set("safesearch", "2")
+	}
+	var body struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := getJSON(ctx, b.client, "searxng", b.endpoint+"?"+values.Encode(), nil, &body); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(body.Results))
+	for _, r := range body.Results {
+		if r.URL == "" {
+			continue
+		}
+		results = append(results, SearchResult{Title: singleLine(r.Title), Description: plainSnippet(r.Content), Link: r.URL})
+	}
+	return results, nil
+}
@@ -0,0 +1,148 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/leonardcser/web-mcp/internal/cache"
+)
+
+// pagedBackend serves total numbered results in backend pages of size,
+// whatever the requested limit.
+type pagedBackend struct {
+	total, size int
+	calls       int
+}
+
+func (b *pagedBackend) Name() string { return "paged" }
+
+func (b *pagedBackend) Search(_ context.Context, req SearchRequest) ([]SearchResult, error) {
+	b.calls++
+	results := []SearchResult{}
+	for i := (req.Page - 1) * b.size; i < min(req.Page*b.size, b.total); i++ {
+		results = append(results, SearchResult{Title: fmt.Sprint(i), Link: fmt.Sprintf("https://r%d.example.com/", i)})
+	}
+	return results, nil
+}
+
+func TestSearchPagesByLimit(t *testing.T) {
+	for _, tc := range []struct{ total, size, limit int }{
+		{total: 45, size: 20, limit: 10},
+		{total: 45, size: 20, limit: 7},
+		{total: 33, size: 11, limit: 10},
+		{total: 60, size: 20, limit: 20},
+		{total: 75, size: 30, limit: 10},
+		{total: 75, size: 30, limit: 20},
+	} {
+		t.Run(fmt.Sprintf("%d/%d/%d", tc.total, tc.size, tc.limit), func(t *testing.T) {
+			backend := &pagedBackend{total: tc.total, size: tc.size}
+			s := NewSearcher(backend, cache.NewMemory(0, time.Minute), SearcherOptions{TTL: time.Minute})
+			next := 0
+			for page := 1; page <= MaxSearchPage; page++ {
+				results, err := s.Search(context.Background(), SearchRequest{Query: "q", Limit: tc.limit, Page: page})
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, r := range results {
+					if r.Title != fmt.Sprint(next) {
+						t.Fatalf("page %d: got result %s, want %d", page, r.Title, next)
+					}
+					next++
+				}
+				if len(results) < tc.limit {
+					break
+				}
+			}
+			if next != tc.total {
+				t.Errorf("paging reached %d of %d results", next, tc.total)
+			}
+			// Each backend page is fetched once, plus one empty page at the end.
+			if want := (tc.total+tc.size-1)/tc.size + 1; backend.calls > want {
+				t.Errorf("backend called %d times, want at most %d", backend.calls, want)
+			}
+		})
+	}
+}
+
+func TestSearchFiltersDeniedHosts(t *testing.T) {
+	backend := &pagedBackend{total: 5, size: 20}
+	policy, err := NewPolicy(nil, []string{"r1.example.com", "r3.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSearcher(backend, cache.NewMemory(0, time.Minute), SearcherOptions{Policy: policy})
+	results, err := s.Search(context.Background(), SearchRequest{Query: "q", Limit: 2, Page: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Title != "4" {
+		t.Fatalf("page 2 = %+v, want only result 4", results)
+	}
+}
+
+// TestSearchKeepsLongProviderPages pages through a SearXNG instance whose
+// pages hold more results than the page size asked of backends.
+func TestSearchKeepsLongProviderPages(t *testing.T) {
+	const total, perPage = 50, 30
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("pageno"))
+		var body struct {
+			Results []map[string]string `json:"results"`
+		}
+		body.Results = []map[string]string{}
+		for i := (page - 1) * perPage; i < min(page*perPage, total); i++ {
+			body.Results = append(body.Results, map[string]string{"title": fmt.Sprint(i), "url": fmt.Sprintf("https://r%d.example.com/", i)})
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer srv.Close()
+
+	s := NewSearcher(NewSearXNGBackend(srv.URL), cache.NewMemory(0, time.Minute), SearcherOptions{TTL: time.Minute})
+	var got []string
+	for page := 1; page <= MaxSearchPage; page++ {
+		results, err := s.Search(context.Background(), SearchRequest{Query: "q", Limit: 20, Page: page})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range results {
+			got = append(got, r.Title)
+		}
+		if len(results) < 20 {
+			break
+		}
+	}
+	if len(got) != total {
+		t.Fatalf("paging returned %d of %d results", len(got), total)
+	}
+	for i, title := range got {
+		if title != fmt.Sprint(i) {
+			t.Fatalf("result %d is %s", i, title)
+		}
+	}
+}
+
+func TestRegionParts(t *testing.T) {
+	for _, tc := range []struct {
+		req           SearchRequest
+		country, lang string
+	}{
+		{SearchRequest{}, "", ""},
+		{SearchRequest{Region: "de-de"}, "de", "de"},
+		{SearchRequest{Region: "wt-wt"}, "", ""},
+		{SearchRequest{Region: "wt-wt", Language: "fr"}, "", "fr"},
+		{SearchRequest{Region: "ch-fr", Language: "de"}, "ch", "de"},
+	} {
+		if got := tc.req.regionCountry(); got != tc.country {
+			t.Errorf("%+v: country = %q, want %q", tc.req, got, tc.country)
+		}
+		if got := tc.req.regionLanguage(); got != tc.lang {
+			t.Errorf("%+v: language = %q, want %q", tc.req, got, tc.lang)
+		}
+	}
+}
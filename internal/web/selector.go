@@ -0,0 +1,173 @@
+package web
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"golang.org/x/net/html"
+)
+
+// maxNearMisses is the number of similar ids and classes suggested when a
+// selector matches nothing.
+const maxNearMisses = 5
+
+// SelectorError reports a selector that matched no element. NearMisses lists
+// ids and classes of the page that resemble it, as CSS selectors.
+type SelectorError struct {
+	Selector   string   `json:"selector"`
+	NearMisses []string `json:"near_misses"`
+}
+
+func (e *SelectorError) Error() string {
+	if len(e.NearMisses) == 0 {
+		return fmt.Sprintf("selector %q matched nothing and the page has no similar ids or classes", e.Selector)
+	}
+	return fmt.Sprintf("selector %q matched nothing; similar elements: %s", e.Selector, strings.Join(e.NearMisses, ", "))
+}
+
+// isXPath reports whether selector is an XPath expression rather than a CSS
+// selector. XPath expressions start with "/", "./" or "(".
+func isXPath(selector string) bool {
+	return strings.HasPrefix(selector, "/") || strings.HasPrefix(selector, "./") || strings.HasPrefix(selector, "(")
+}
+
+// selectNodes returns the elements of doc matched by selector, dropping
+// those nested inside another match so no content is repeated.
+func selectNodes(doc *goquery.Document, selector string) (*goquery.Selection, error) {
+	var nodes []*html.Node
+	if isXPath(selector) {
+		found, err := htmlquery.QueryAll(doc.Nodes[0], selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid XPath selector %q: %w", selector, err)
+		}
+		for _, n := range found {
+			if n.Type == html.ElementNode {
+				nodes = append(nodes, n)
+			}
+		}
+	} else {
+		m, err := cascadia.Compile(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSS selector %q: %w", selector, err)
+		}
+		nodes = doc.FindMatcher(m).Nodes
+	}
+	matched := make(map[*html.Node]bool, len(nodes))
+	for _, n := range nodes {
+		matched[n] = true
+	}
+	nodes = slices.DeleteFunc(nodes, func(n *html.Node) bool {
+		for p := n.Parent; p != nil; p = p.Parent {
+			if matched[p] {
+				return true
+			}
+		}
+		return false
+	})
+	if len(nodes) == 0 {
+		return nil, &SelectorError{Selector: selector, NearMisses: nearMisses(doc, selector)}
+	}
+	return doc.FindNodes(nodes...), nil
+}
+
+// scopeToSelector replaces the body of doc with the elements matched by
+// selector, in document order. Selecting the body itself, or an ancestor of
+// it, keeps the whole page.
+func scopeToSelector(doc *goquery.Document, selector string) error {
+	matches, err := selectNodes(doc, selector)
+	if err != nil {
+		return err
+	}
+	body := doc.Find("body").First()
+	if matches.Has("body").Length() > 0 || matches.Is("body") {
+		return nil
+	}
+	matches.Remove()
+	body.Empty()
+	body.AppendSelection(matches)
+	return nil
+}
+
+var selectorWord = regexp.MustCompile(`[A-Za-z0-9_-]{3,}`)
+
+// nearMisses returns the ids and classes of doc closest to the words of
+// selector, best first.
+func nearMisses(doc *goquery.Document, selector string) []string {
+	words := selectorWord.FindAllString(strings.ToLower(selector), -1)
+	if len(words) == 0 {
+		return nil
+	}
+	type candidate struct {
+		sel   string
+		score float64
+	}
+	var found []candidate
+	seen := make(map[string]bool)
+	consider := func(sel, name string) {
+		if name == "" || seen[sel] {
+			return
+		}
+		seen[sel] = true
+		best := 1.0
+		for _, w := range words {
+			best = min(best, nameDistance(w, strings.ToLower(name)))
+		}
+		if best <= 0.5 {
+			found = append(found, candidate{sel, best})
+		}
+	}
+	doc.Find("[id], [class]").Each(func(_ int, s *goquery.Selection) {
+		if id, ok := s.Attr("id"); ok {
+			consider("#"+id, id)
+		}
+		for _, class := range strings.Fields(s.AttrOr("class", "")) {
+			consider("."+class, class)
+		}
+	})
+	slices.SortStableFunc(found, func(a, b candidate) int { return cmp.Compare(a.score, b.score) })
+	var out []string
+	for _, c := range found[:min(len(found), maxNearMisses)] {
+		out = append(out, c.sel)
+	}
+	return out
+}
+
+// nameDistance scores how different two names are, from 0 (identical) to 1.
+// A name containing the other scores by their length difference, otherwise
+// the edit distance is used.
+func nameDistance(a, b string) float64 {
+	if a == b {
+		return 0
+	}
+	longest := float64(max(len(a), len(b)))
+	if strings.Contains(a, b) || strings.Contains(b, a) {
+		return 0.5 * float64(max(len(a), len(b))-min(len(a), len(b))) / longest
+	}
+	return float64(levenshtein(a, b)) / longest
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
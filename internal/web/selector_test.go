@@ -0,0 +1,155 @@
+package web
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const selectorPage = `<html><body>
+<nav id="site-nav"><a href="/">Home</a></nav>
+<main>
+<section id="api-reference" class="docs reference"><h2>API</h2><p>Call <code>run()</code>.</p>
+<div class="note"><p>Nested note.</p></div></section>
+<table class="changelog"><tr><td>v2: faster</td></tr></table>
+<div class="note"><p>Outer note.</p></div>
+</main>
+<footer class="site-footer">Footer text</footer>
+</body></html>`
+
+func summarizeSelector(selector string) (*PageSummary, error) {
+	return summarize(&response{
+		finalURL:    "https://example.com/docs/page",
+		contentType: "text/html; charset=utf-8",
+		body:        []byte(selectorPage),
+	}, FetchOptions{Mode: ModeFull, Selector: selector})
+}
+
+func TestSelector(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		selector string
+		want     []string
+		notWant  []string
+	}{
+		{name: "css id", selector: "#api-reference", want: []string{"API", "run()", "Nested note."}, notWant: []string{"Home", "v2: faster", "Footer text"}},
+		{name: "css class", selector: "table.changelog", want: []string{"v2: faster"}, notWant: []string{"API", "Footer text"}},
+		{name: "css list in document order", selector: "footer, table.changelog", want: []string{"v2: faster", "Footer text"}, notWant: []string{"API"}},
+		{name: "nested matches kept once", selector: "section, .note", want: []string{"Nested note.", "Outer note."}, notWant: []string{"v2: faster"}},
+		{name: "css body keeps the page", selector: "body", want: []string{"Home", "API", "Footer text"}},
+		{name: "xpath", selector: "//table[@class='changelog']", want: []string{"v2: faster"}, notWant: []string{"API"}},
+		{name: "xpath relative", selector: ".//footer", want: []string{"Footer text"}, notWant: []string{"API"}},
+		{name: "xpath union", selector: "(//nav | //footer)", want: []string{"Home", "Footer text"}, notWant: []string{"API"}},
+		{name: "xpath text nodes are ignored", selector: "//footer | //footer/text()", want: []string{"Footer text"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ps, err := summarizeSelector(tc.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tc.want {
+				if strings.Count(ps.Text, w) != 1 {
+					t.Errorf("text has %d copies of %q, want 1:\n%s", strings.Count(ps.Text, w), w, ps.Text)
+				}
+			}
+			for _, w := range tc.notWant {
+				if strings.Contains(ps.Text, w) {
+					t.Errorf("text contains %q:\n%s", w, ps.Text)
+				}
+			}
+			if i, j := strings.Index(ps.Text, "v2: faster"), strings.Index(ps.Text, "Footer text"); i >= 0 && j >= 0 && i > j {
+				t.Errorf("matches out of document order:\n%s", ps.Text)
+			}
+		})
+	}
+}
+
+func TestInvalidSelector(t *testing.T) {
+	for _, tc := range []struct{ selector, want string }{
+		{"div[", "invalid CSS selector"},
+		{"//div[", "invalid XPath selector"},
+	} {
+		_, err := summarizeSelector(tc.selector)
+		var se *SelectorError
+		if err == nil || !strings.Contains(err.Error(), tc.want) || errors.As(err, &se) {
+			t.Errorf("%q: err = %v, want %s", tc.selector, err, tc.want)
+		}
+	}
+}
+
+func TestSelectorNearMisses(t *testing.T) {
+	for _, tc := range []struct {
+		selector string
+		want     []string
+	}{
+		{"#api-refrence", []string{"#api-reference", ".reference"}},
+		{".changelogs", []string{".changelog"}},
+		{"#reference", []string{".reference", "#api-reference"}},
+		{"footer.sitefooter", []string{".site-footer", ".note"}},
+		{"//div[@id='nav']", []string{"#site-nav"}},
+		{"#zzzzzz", nil},
+		{"p > b", nil},
+	} {
+		_, err := summarizeSelector(tc.selector)
+		var se *SelectorError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: err = %v, want a SelectorError", tc.selector, err)
+			continue
+		}
+		if se.Selector != tc.selector || !reflect.DeepEqual(se.NearMisses, tc.want) {
+			t.Errorf("%q: near misses %q, want %q", tc.selector, se.NearMisses, tc.want)
+		}
+	}
+}
+
+func TestSelectorErrorMessage(t *testing.T) {
+	err := &SelectorError{Selector: "#x", NearMisses: []string{"#y", ".x"}}
+	if got, want := err.Error(), `selector "#x" matched nothing; similar elements: #y, .x`; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	err.NearMisses = nil
+	if got := err.Error(); !strings.Contains(got, "no similar ids or classes") {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"same", "same", 0},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"reference", "refrence", 1},
+		{"ab", "ba", 2},
+	} {
+		if got := levenshtein(tc.a, tc.b); got != tc.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+		if got := levenshtein(tc.b, tc.a); got != tc.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tc.b, tc.a, got, tc.want)
+		}
+	}
+}
+
+func TestNameDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want float64
+	}{
+		{"nav", "nav", 0},
+		{"nav", "site-nav", 0.5 * 5 / 8},
+		{"changelog", "changelogs", 0.5 * 1 / 10},
+		{"reference", "refrence", 1.0 / 9},
+		{"abc", "xyz", 1},
+	} {
+		if got := nameDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("nameDistance(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
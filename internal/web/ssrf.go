@@ -0,0 +1,147 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// BlockedAddressError is returned when a fetch would connect to a private,
+// loopback, link-local or otherwise internal address that is not allowlisted.
+type BlockedAddressError struct {
+	Host   string
+	Addr   netip.Addr
+	Reason string
+}
+
+func (e *BlockedAddressError) Error() string {
+	if e.Host == e.Addr.String() {
+		return fmt.Sprintf("blocked request to %s: %s addresses are not allowed (add it to the fetch allowlist to permit it)", e.Host, e.Reason)
+	}
+	return fmt.Sprintf("blocked request to %s: it resolves to %s, a %s address (add it to the fetch allowlist to permit it)", e.Host, e.Addr, e.Reason)
+}
+
+var (
+	sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+	thisNetwork        = netip.MustParsePrefix("0.0.0.0/8")
+	nat64Prefix        = netip.MustParsePrefix("64:ff9b::/96")
+	broadcast          = netip.MustParseAddr("255.255.255.255")
+)
+
+// blockedReason describes why addr must not be contacted, or returns "" for
+// public addresses.
+func blockedReason(addr netip.Addr) string {
+	addr = addr.Unmap()
+	if nat64Prefix.Contains(addr) {
+		// Check the IPv4 address embedded in a NAT64 address.
+		b := addr.As16()
+		addr = netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]})
+	}
+	switch {
+	case addr.IsLoopback():
+		return "loopback"
+	case addr.IsPrivate(), sharedAddressSpace.Contains(addr):
+		return "private"
+	case addr.IsLinkLocalUnicast(), addr.IsLinkLocalMulticast():
+		return "link-local"
+	case addr.IsMulticast(), addr == broadcast:
+		return "multicast"
+	case addr.IsUnspecified(), thisNetwork.Contains(addr):
+		return "unspecified"
+	}
+	return ""
+}
+
+// addressGuard rejects connections to internal addresses. The check runs in
+// the dialer after DNS resolution, so it covers every redirect hop and
+// cannot be bypassed by a hostname that later resolves to another address.
+type addressGuard struct {
+	allowPrefixes []netip.Prefix
+	allowHosts    map[string]bool
+}
+
+// newAddressGuard builds a guard that permits the given CIDR ranges, IP
+// addresses and hostnames.
+func newAddressGuard(allow []string) (*addressGuard, error) {
+	g := &addressGuard{allowHosts: make(map[string]bool)}
+	for _, entry := range allow {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			p, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowlist entry %q: %w", entry, err)
+			}
+			g.allowPrefixes = append(g.allowPrefixes, p.Masked())
+		default:
+			if addr, err := netip.ParseAddr(entry); err == nil {
+				g.allowPrefixes = append(g.allowPrefixes, netip.PrefixFrom(addr, addr.BitLen()))
+				continue
+			}
+			g.allowHosts[strings.ToLower(entry)] = true
+		}
+	}
+	return g, nil
+}
+
+// check returns a BlockedAddressError when addr, reached through host, is
+// internal and not allowlisted.
+func (g *addressGuard) check(host string, addr netip.Addr) error {
+	reason := blockedReason(addr)
+	if reason == "" || g.allowHosts[strings.ToLower(host)] {
+		return nil
+	}
+	for _, p := range g.allowPrefixes {
+		if p.Contains(addr.Unmap()) || p.Contains(addr) {
+			return nil
+		}
+	}
+	return &BlockedAddressError{Host: host, Addr: addr.Unmap(), Reason: reason}
+}
+
+// checkHost rejects hosts that are literal internal addresses or localhost
+// before any connection is made.
+func (g *addressGuard) checkHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		if g.allowHosts[host] {
+			return nil
+		}
+		return &BlockedAddressError{Host: host, Addr: netip.IPv6Loopback(), Reason: "loopback"}
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return g.check(addr.String(), addr)
+	}
+	return nil
+}
+
+// transport returns an http.Transport whose dialer enforces the guard.
+// Proxies from the environment are not used: the dialer would only see the
+// proxy's address, and the proxy would resolve the target unchecked.
+func (g *addressGuard) transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		d := *dialer
+		d.Control = func(_, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			return g.check(host, ap.Addr())
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+	return t
+}
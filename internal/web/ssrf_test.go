@@ -0,0 +1,72 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+)
+
+// TestFetchIgnoresEnvironmentProxy runs in a child process because
+// http.ProxyFromEnvironment reads the environment only once per process.
+func TestFetchIgnoresEnvironmentProxy(t *testing.T) {
+	if os.Getenv("WEB_MCP_PROXY_CHILD") != "" {
+		// internal.test does not resolve, so the fetch can only succeed
+		// through the proxy, which the guard cannot check.
+		f := newTestFetcher(t, FetcherOptions{})
+		if ps, err := f.Fetch(context.Background(), "http://internal.test/metadata", FetchOptions{}); err == nil {
+			t.Fatalf("fetch went through the proxy and returned %q", ps.Text)
+		}
+		return
+	}
+
+	var hits atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "instance credentials")
+	}))
+	defer proxy.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFetchIgnoresEnvironmentProxy$")
+	cmd.Env = append(os.Environ(), "WEB_MCP_PROXY_CHILD=1", "HTTP_PROXY="+proxy.URL, "http_proxy="+proxy.URL, "NO_PROXY=", "no_proxy=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("child process: %v\n%s", err, out)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("proxy received %d requests", n)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	g, err := newAddressGuard([]string{"10.1.0.0/16", "wiki.localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		host    string
+		blocked bool
+	}{
+		{"example.com", false},
+		{"localhost", true},
+		{"LOCALHOST.", true},
+		{"api.localhost", true},
+		{"wiki.localhost", false},
+		{"127.0.0.1", true},
+		{"[::1]", true},
+		{"169.254.169.254", true},
+		{"192.168.1.1", true},
+		{"10.1.2.3", false},
+		{"::ffff:10.2.0.1", true},
+		{"64:ff9b::7f00:1", true},
+		{"93.184.216.34", false},
+	} {
+		if err := g.checkHost(tc.host); (err != nil) != tc.blocked {
+			t.Errorf("checkHost(%q) = %v, want blocked %v", tc.host, err, tc.blocked)
+		}
+	}
+}
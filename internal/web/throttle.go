@@ -0,0 +1,83 @@
+package web
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// hostThrottle limits the number of in-flight requests per host and keeps a
+// minimum delay between the end of one request and the start of the next
+// one to the same host. It is safe for concurrent use.
+type hostThrottle struct {
+	parallelism int
+	delay       time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlots
+}
+
+// hostSlots tracks one host. users counts the requests waiting for, holding
+// or still delaying the release of a slot; the host is forgotten when it
+// drops to zero, so the map only holds hosts that are in use.
+type hostSlots struct {
+	sem   chan struct{}
+	users int
+}
+
+func newHostThrottle(parallelism int, delay time.Duration) *hostThrottle {
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	return &hostThrottle{
+		parallelism: parallelism,
+		delay:       delay,
+		hosts:       make(map[string]*hostSlots),
+	}
+}
+
+// acquire blocks until a slot for host is available or ctx is done. The
+// returned release func frees the slot after the configured delay.
+func (t *hostThrottle) acquire(ctx context.Context, host string) (func(), error) {
+	t.mu.Lock()
+	h, ok := t.hosts[host]
+	if !ok {
+		h = &hostSlots{sem: make(chan struct{}, t.parallelism)}
+		t.hosts[host] = h
+	}
+	h.users++
+	t.mu.Unlock()
+
+	select {
+	case h.sem <- struct{}{}:
+	case <-ctx.Done():
+		t.done(host, h)
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			free := func() {
+				<-h.sem
+				t.done(host, h)
+			}
+			if t.delay <= 0 {
+				free()
+				return
+			}
+			time.AfterFunc(t.delay, free)
+		})
+	}
+	return release, nil
+}
+
+// done records that a user of h is finished and forgets host once h is
+// idle.
+func (t *hostThrottle) done(host string, h *hostSlots) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h.users--
+	if h.users == 0 {
+		delete(t.hosts, host)
+	}
+}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func (t *hostThrottle) size() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.hosts)
+}
+
+func TestHostThrottleDelaysNextRequest(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	th := newHostThrottle(1, delay)
+	release, err := th.acquire(context.Background(), "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	start := time.Now()
+	release, err = th.acquire(context.Background(), "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if waited := time.Since(start); waited < delay/2 {
+		t.Errorf("second request started after %v, want about %v", waited, delay)
+	}
+
+	// Other hosts are not held up.
+	ctx, cancel := context.WithTimeout(context.Background(), delay/5)
+	defer cancel()
+	release, err = th.acquire(ctx, "b.example")
+	if err != nil {
+		t.Fatalf("acquire on another host: %v", err)
+	}
+	release()
+}
+
+func TestHostThrottleForgetsIdleHosts(t *testing.T) {
+	th := newHostThrottle(2, time.Millisecond)
+	for i := range 100 {
+		release, err := th.acquire(context.Background(), fmt.Sprintf("h%d.example", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+
+	// A request that gives up waiting is forgotten as well.
+	held, err := th.acquire(context.Background(), "busy.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	held2, err := th.acquire(context.Background(), "busy.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := th.acquire(ctx, "busy.example"); err == nil {
+		t.Fatal("acquire on a full host succeeded with a cancelled context")
+	}
+	held()
+	held2()
+
+	deadline := time.Now().Add(time.Second)
+	for th.size() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d idle hosts are still tracked", th.size())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}